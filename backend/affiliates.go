@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jules-playground/lms-backend/commission"
+	"github.com/jules-playground/lms-backend/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Afiliados ---
+
+const (
+	AffiliateActive   = "Ativo"
+	AffiliateInactive = "Inativo"
+)
+
+// affiliateRequest é o corpo de POST e PUT. No PUT só os campos enviados
+// mudam: a tela de edição manda apenas nome, contato, taxa e chave PIX.
+// Indicações e valor ganho são do servidor e não são aceitos.
+type affiliateRequest struct {
+	Name            *string      `json:"name"`
+	Email           *string      `json:"email"`
+	Phone           *string      `json:"phone"`
+	Code            *string      `json:"code"`
+	CommissionBase  *string      `json:"commissionBase"`
+	CommissionRate  *float64     `json:"commissionRate"`
+	FixedCommission *money.Cents `json:"fixedCommission"`
+	Status          *string      `json:"status"`
+	PixKey          *string      `json:"pixKey"`
+}
+
+func (req affiliateRequest) apply(a *Affiliate) {
+	set := func(dst *string, src *string) {
+		if src != nil {
+			*dst = strings.TrimSpace(*src)
+		}
+	}
+	set(&a.Name, req.Name)
+	set(&a.Email, req.Email)
+	set(&a.Phone, req.Phone)
+	set(&a.Code, req.Code)
+	set(&a.CommissionBase, req.CommissionBase)
+	set(&a.Status, req.Status)
+	set(&a.PixKey, req.PixKey)
+	if req.CommissionRate != nil {
+		a.CommissionRate = *req.CommissionRate
+	}
+	if req.FixedCommission != nil {
+		a.FixedCommission = *req.FixedCommission
+	}
+}
+
+// validateAffiliate normaliza o afiliado e devolve os erros por campo.
+func validateAffiliate(a *Affiliate) fieldErrors {
+	fe := fieldErrors{}
+	if a.Name == "" {
+		fe["name"] = "Informe o nome"
+	}
+	if a.Email != "" && (!strings.Contains(a.Email, "@") || strings.ContainsAny(a.Email, " ,;")) {
+		fe["email"] = "E-mail inválido"
+	}
+	if a.CommissionRate < 0 || a.CommissionRate > 100 {
+		fe["commissionRate"] = "Comissão deve ficar entre 0% e 100%"
+	}
+	if a.FixedCommission < 0 {
+		fe["fixedCommission"] = "Comissão fixa não pode ser negativa"
+	}
+	if base, err := commission.NormalizeBase(a.CommissionBase); err != nil {
+		fe["commissionBase"] = "Base da comissão deve ser ORIGINACAO ou JUROS"
+	} else {
+		a.CommissionBase = base
+	}
+	switch strings.ToLower(a.Status) {
+	case "", "ativo":
+		a.Status = AffiliateActive
+	case "inativo":
+		a.Status = AffiliateInactive
+	default:
+		fe["status"] = "Situação deve ser Ativo ou Inativo"
+	}
+	a.Code = strings.ToUpper(a.Code)
+	if a.Code == "" && a.Name != "" {
+		a.Code = newAffiliateCode(a.Name)
+	}
+	return fe
+}
+
+// affiliateCodeAttempts é quantas vezes o código é sorteado de novo quando colide.
+const affiliateCodeAttempts = 5
+
+// newAffiliateCode gera o código de indicação no formato da tela: REF-NOME-123.
+func newAffiliateCode(name string) string {
+	first := strings.ToUpper(strings.Fields(name)[0])
+	if r := []rune(first); len(r) > 4 {
+		first = string(r[:4])
+	}
+	return fmt.Sprintf("REF-%s-%d", first, rand.Intn(1000))
+}
+
+// checkAffiliateCode impede dois afiliados com o mesmo código de indicação.
+func checkAffiliateCode(ctx context.Context, a Affiliate) (fieldErrors, error) {
+	var other Affiliate
+	err := affiliateCollection.FindOne(ctx, bson.M{"code": a.Code, "id": bson.M{"$ne": a.ID}}).Decode(&other)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fieldErrors{"code": fmt.Sprintf("Código já usado por %s", other.Name)}, nil
+}
+
+// saveAffiliate valida e grava o afiliado, respondendo o erro quando falha.
+func saveAffiliate(ctx context.Context, w http.ResponseWriter, a *Affiliate, insert bool) bool {
+	generated := strings.TrimSpace(a.Code) == ""
+	if fe := validateAffiliate(a); len(fe) > 0 {
+		writeFieldErrors(w, http.StatusBadRequest, fe)
+		return false
+	}
+	fe, err := checkAffiliateCode(ctx, *a)
+	// O usuário não tem como resolver a colisão de um código sorteado: tenta outros.
+	for attempt := 0; generated && err == nil && len(fe) > 0 && attempt < affiliateCodeAttempts; attempt++ {
+		a.Code = newAffiliateCode(a.Name)
+		fe, err = checkAffiliateCode(ctx, *a)
+	}
+	if err == nil && len(fe) > 0 {
+		writeFieldErrors(w, http.StatusConflict, fe)
+		return false
+	}
+	if err == nil {
+		if insert {
+			_, err = affiliateCollection.InsertOne(ctx, a)
+		} else {
+			_, err = affiliateCollection.ReplaceOne(ctx, bson.M{"id": a.ID}, a)
+		}
+	}
+	if err != nil {
+		http.Error(w, "Erro ao salvar afiliado", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// affiliatesHandler atende GET (lista) e POST (cadastro) em /api/affiliates.
+func affiliatesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	switch r.Method {
+	case http.MethodGet:
+		cursor, err := affiliateCollection.Find(ctx, bson.M{})
+		if err != nil {
+			http.Error(w, "Erro ao buscar afiliados", http.StatusInternalServerError)
+			return
+		}
+		var res []Affiliate
+		cursor.All(ctx, &res)
+		if res == nil {
+			res = []Affiliate{}
+		}
+		if err := fillAffiliateTotals(ctx, res); err != nil {
+			http.Error(w, "Erro ao calcular comissões", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	case http.MethodPost:
+		var req affiliateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		a := Affiliate{ID: primitive.NewObjectID().Hex()}
+		req.apply(&a)
+		if !saveAffiliate(ctx, w, &a, true) {
+			return
+		}
+		logUserAction("AFILIADO CRIADO", currentUser(r), fmt.Sprintf("Afiliado %s (%s), código %s, comissão %.2f%% (%s) + %s", a.ID, a.Name, a.Code, a.CommissionRate, a.CommissionBase, a.FixedCommission))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(a)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// affiliateUpdateHandler atende GET, PUT e DELETE em /api/affiliates/{id}.
+func affiliateUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/affiliates/"), "/")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var current Affiliate
+	if err := affiliateCollection.FindOne(ctx, bson.M{"id": id}).Decode(&current); err == mongo.ErrNoDocuments {
+		http.Error(w, "Afiliado não encontrado", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Erro ao buscar afiliado", http.StatusInternalServerError)
+		return
+	}
+	switch action {
+	case "":
+	case "commissions":
+		affiliateCommissionsHandler(w, r, current)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	one := []Affiliate{current}
+	if err := fillAffiliateTotals(ctx, one); err != nil {
+		http.Error(w, "Erro ao calcular comissões", http.StatusInternalServerError)
+		return
+	}
+	current = one[0]
+	user := currentUser(r)
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(current)
+	case http.MethodPut:
+		var req affiliateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		a := current
+		req.apply(&a)
+		if !saveAffiliate(ctx, w, &a, false) {
+			return
+		}
+		logUserAction("AFILIADO EDITADO", user, fmt.Sprintf("Afiliado %s (%s), código %s, comissão %.2f%% (%s) + %s, situação %s", a.ID, a.Name, a.Code, a.CommissionRate, a.CommissionBase, a.FixedCommission, a.Status))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(a)
+	case http.MethodDelete:
+		// Contratos vinculados guardam o ID; sem o cadastro o extrato de comissões se perde.
+		linked, err := loanCollection.CountDocuments(ctx, bson.M{"affiliateId": id})
+		if err != nil {
+			http.Error(w, "Erro ao buscar contratos do afiliado", http.StatusInternalServerError)
+			return
+		}
+		if linked > 0 {
+			http.Error(w, fmt.Sprintf("Afiliado possui %d contrato(s) vinculado(s); inative-o em vez de remover", linked), http.StatusConflict)
+			return
+		}
+		if _, err := affiliateCollection.DeleteOne(ctx, bson.M{"id": id}); err != nil {
+			http.Error(w, "Erro ao remover afiliado", http.StatusInternalServerError)
+			return
+		}
+		logUserAction("AFILIADO REMOVIDO", user, fmt.Sprintf("Afiliado %s (%s), código %s", id, current.Name, current.Code))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// --- Comissões ---
+
+// linkAffiliate vincula o contrato ao afiliado pelo ID ou pelo código. Telas
+// antigas só mandam affiliateName, que pode trazer o nome ou o código. As
+// condições de comissão vigentes ficam congeladas no contrato.
+func linkAffiliate(ctx context.Context, l *Loan) (fieldErrors, error) {
+	var filter bson.M
+	field := "affiliateId"
+	switch name := strings.TrimSpace(l.AffiliateName); {
+	case l.AffiliateID != "":
+		filter = bson.M{"id": l.AffiliateID}
+	case strings.TrimSpace(l.AffiliateCode) != "":
+		filter = bson.M{"code": strings.ToUpper(strings.TrimSpace(l.AffiliateCode))}
+		field = "affiliateCode"
+	case name != "":
+		filter = bson.M{"$or": []bson.M{
+			{"code": strings.ToUpper(name)},
+			{"name": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(name) + "$", Options: "i"}},
+		}}
+		field = "affiliateName"
+	default:
+		l.AffiliateCommission, l.AffiliateFee = nil, 0
+		return nil, nil
+	}
+	var a Affiliate
+	if err := affiliateCollection.FindOne(ctx, filter).Decode(&a); err == mongo.ErrNoDocuments {
+		return fieldErrors{field: "Afiliado não encontrado"}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if a.Status == AffiliateInactive {
+		return fieldErrors{field: fmt.Sprintf("Afiliado %s está inativo", a.Name)}, nil
+	}
+	base, _ := commission.NormalizeBase(a.CommissionBase)
+	l.AffiliateID, l.AffiliateCode, l.AffiliateName = a.ID, a.Code, a.Name
+	l.AffiliateCommission = &commission.Terms{Base: base, Rate: a.CommissionRate, Fixed: a.FixedCommission}
+	l.refreshAffiliateFee()
+	return nil, nil
+}
+
+// refreshAffiliateFee recalcula a comissão do contrato vinculado. Na base JUROS
+// ela acompanha os juros recebidos, então é refeita a cada gravação.
+func (l *Loan) refreshAffiliateFee() {
+	if l.AffiliateCommission != nil {
+		l.AffiliateFee = l.AffiliateCommission.Amount(l.Amount, l.TotalPaidInterest)
+	}
+}
+
+// affiliateLoans busca os contratos vinculados aos afiliados informados.
+func affiliateLoans(ctx context.Context, ids ...string) ([]Loan, error) {
+	cursor, err := loanCollection.Find(ctx, bson.M{"affiliateId": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{
+		"id": 1, "client": 1, "startDate": 1, "status": 1, "amount": 1, "totalPaidInterest": 1,
+		"affiliateId": 1, "affiliateCommission": 1, "refinancedFrom": 1,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	var loans []Loan
+	err = cursor.All(ctx, &loans)
+	return loans, err
+}
+
+// fillAffiliateTotals deriva indicações e valor ganho dos contratos vinculados.
+// Refinanciamentos somam comissão mas não contam como nova indicação.
+func fillAffiliateTotals(ctx context.Context, affiliates []Affiliate) error {
+	ids := make([]string, len(affiliates))
+	for i, a := range affiliates {
+		ids[i] = a.ID
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	loans, err := affiliateLoans(ctx, ids...)
+	if err != nil {
+		return err
+	}
+	idx := map[string]*Affiliate{}
+	for i := range affiliates {
+		affiliates[i].Referrals, affiliates[i].Earned = 0, 0
+		idx[affiliates[i].ID] = &affiliates[i]
+	}
+	for _, l := range loans {
+		if a := idx[l.AffiliateID]; a != nil {
+			if l.RefinancedFrom == "" {
+				a.Referrals++
+			}
+			a.Earned += loanCommission(l)
+		}
+	}
+	return nil
+}
+
+func loanCommission(l Loan) money.Cents {
+	if l.AffiliateCommission == nil {
+		return 0
+	}
+	return l.AffiliateCommission.Amount(l.Amount, l.TotalPaidInterest)
+}
+
+// AffiliateCommissionLine é a comissão de um contrato no extrato do afiliado.
+type AffiliateCommissionLine struct {
+	LoanID           string            `json:"loanId"`
+	Client           string            `json:"client"`
+	StartDate        string            `json:"startDate"`
+	Status           string            `json:"status"`
+	RefinancedFrom   string            `json:"refinancedFrom,omitempty"` // refinanciamento: não conta como indicação
+	Amount           money.Cents       `json:"amount"`
+	InterestReceived money.Cents       `json:"interestReceived"`
+	Terms            *commission.Terms `json:"terms"`
+	Commission       money.Cents       `json:"commission"`
+}
+
+// AffiliateStatement é o extrato de comissões do afiliado.
+type AffiliateStatement struct {
+	Affiliate Affiliate                 `json:"affiliate"`
+	Loans     []AffiliateCommissionLine `json:"loans"`
+}
+
+// affiliateCommissionsHandler atende GET /api/affiliates/{id}/commissions.
+func affiliateCommissionsHandler(w http.ResponseWriter, r *http.Request, a Affiliate) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	loans, err := affiliateLoans(ctx, a.ID)
+	if err != nil {
+		http.Error(w, "Erro ao calcular comissões", http.StatusInternalServerError)
+		return
+	}
+	st := AffiliateStatement{Affiliate: a, Loans: []AffiliateCommissionLine{}}
+	st.Affiliate.Referrals, st.Affiliate.Earned = 0, 0
+	for _, l := range loans {
+		c := loanCommission(l)
+		if l.RefinancedFrom == "" {
+			st.Affiliate.Referrals++
+		}
+		st.Affiliate.Earned += c
+		st.Loans = append(st.Loans, AffiliateCommissionLine{
+			LoanID: l.ID, Client: l.Client, StartDate: l.StartDate, Status: l.Status, RefinancedFrom: l.RefinancedFrom,
+			Amount: l.Amount, InterestReceived: l.TotalPaidInterest, Terms: l.AffiliateCommission, Commission: c,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
+
+// migrateAffiliateLinks vincula pelo nome (ou código) os contratos antigos que
+// só tinham affiliateName. A comissão digitada à mão vira valor fixo do
+// contrato, para o total ganho não mudar; sem ela valem as condições atuais.
+func migrateAffiliateLinks(ctx context.Context) (string, error) {
+	cursor, err := affiliateCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return "", err
+	}
+	var affiliates []Affiliate
+	if err := cursor.All(ctx, &affiliates); err != nil {
+		return "", err
+	}
+	byKey := map[string]*Affiliate{}
+	for i := range affiliates {
+		a := &affiliates[i]
+		byKey[strings.ToLower(strings.TrimSpace(a.Name))] = a
+		if a.Code != "" {
+			byKey[strings.ToLower(a.Code)] = a
+		}
+	}
+
+	cursor, err = loanCollection.Find(ctx, bson.M{
+		"affiliateName": bson.M{"$exists": true, "$ne": ""},
+		"affiliateId":   bson.M{"$in": bson.A{nil, ""}},
+	}, options.Find().SetProjection(bson.M{"affiliateName": 1, "affiliateFee": 1, "amount": 1, "totalPaidInterest": 1}))
+	if err != nil {
+		return "", err
+	}
+	defer cursor.Close(ctx)
+	linked, unmatched := 0, 0
+	for cursor.Next(ctx) {
+		var l Loan
+		if err := cursor.Decode(&l); err != nil {
+			return "", err
+		}
+		a := byKey[strings.ToLower(strings.TrimSpace(l.AffiliateName))]
+		if a == nil {
+			unmatched++
+			continue
+		}
+		terms := commission.Terms{Base: commission.BaseOrigination, Fixed: l.AffiliateFee}
+		if l.AffiliateFee == 0 {
+			base, _ := commission.NormalizeBase(a.CommissionBase)
+			terms = commission.Terms{Base: base, Rate: a.CommissionRate, Fixed: a.FixedCommission}
+		}
+		set := bson.M{
+			"affiliateId": a.ID, "affiliateCode": a.Code, "affiliateName": a.Name,
+			"affiliateCommission": terms, "affiliateFee": terms.Amount(l.Amount, l.TotalPaidInterest),
+		}
+		if _, err := loanCollection.UpdateOne(ctx, bson.M{"_id": cursor.Current.Lookup("_id")}, bson.M{"$set": set}); err != nil {
+			return "", err
+		}
+		linked++
+	}
+	if err := cursor.Err(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d contrato(s) vinculados a afiliados, %d sem afiliado correspondente", linked, unmatched), nil
+}
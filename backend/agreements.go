@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jules-playground/lms-backend/finance"
+	"github.com/jules-playground/lms-backend/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// --- Acordos (Renegociação) ---
+
+// Situações de um acordo.
+const (
+	AgreementActive    = "Ativo"
+	AgreementFulfilled = "Cumprido"
+)
+
+var (
+	errInvalidAgreement = errors.New("Informe parcelas, primeiro vencimento e desconto entre 0 e 100%")
+	errAgreementActive  = errors.New("Contrato já possui acordo ativo")
+	errNothingOverdue   = errors.New("Contrato não possui saldo vencido para renegociar")
+	errAgreementValue   = errors.New("Valor do acordo não cobre o capital vencido")
+)
+
+type agreementRequest struct {
+	Installments        int         `json:"installments"`
+	InstallmentValue    money.Cents `json:"installmentValue"`
+	FirstDueDate        string      `json:"firstDueDate"`
+	Frequency           string      `json:"frequency"`
+	ChargesDiscountRate float64     `json:"chargesDiscountRate"` // % de desconto sobre multa e mora
+	CorrectionIndex     string      `json:"correctionIndex"`     // vazio usa o índice do contrato
+	Note                string      `json:"note"`
+}
+
+// refreshAgreementStatus recalcula as parcelas do acordo e marca como cumprido
+// quando todas estiverem pagas.
+func refreshAgreementStatus(a *Agreement, ref time.Time) {
+	refreshInstallmentStatus(a.Schedule, ref)
+	a.Status = AgreementFulfilled
+	for _, in := range a.Schedule {
+		if in.Status != InstallmentPaid {
+			a.Status = AgreementActive
+			return
+		}
+	}
+}
+
+// buildAgreementSchedule divide o total em parcelas de mesmo valor; o capital
+// congelado é amortizado em partes iguais e o restante conta como juros/encargos.
+// A última parcela absorve os resíduos de arredondamento. Os vencimentos seguem
+// a regra de dia útil do contrato (adjust nil mantém as datas calculadas).
+func buildAgreementSchedule(total, capital money.Cents, n int, first time.Time, frequency string, adjust func(time.Time) time.Time) []LoanInstallment {
+	dates := finance.DueDates(first, frequency, n, adjust)
+	value := total.Div(n)
+	capitalPart := capital.Div(n)
+	items := make([]LoanInstallment, n)
+	totalLeft, capitalLeft := total, capital
+	for i := range items {
+		payment, c := value, capitalPart
+		if i == n-1 {
+			payment, c = totalLeft, capitalLeft
+		}
+		items[i] = LoanInstallment{
+			Number:           i + 1,
+			DueDate:          dates[i].Format(finance.DateLayout),
+			ExpectedCapital:  c,
+			ExpectedInterest: payment - c,
+			Status:           InstallmentPending,
+		}
+		totalLeft -= payment
+		capitalLeft -= c
+	}
+	return items
+}
+
+// createAgreement congela o saldo vencido do contrato (capital, juros, multa,
+// mora e correção na data de hoje), aplica o desconto sobre multa e mora e cria o
+// novo plano, que pode ser corrigido por índice desde a criação. As parcelas
+// originais ficam no cronograma marcadas como renegociadas.
+func createAgreement(ctx context.Context, id string, req agreementRequest, user string) (Loan, error) {
+	first, err := parseLoanDate(req.FirstDueDate)
+	if err != nil || req.Installments <= 0 || req.ChargesDiscountRate < 0 || req.ChargesDiscountRate > 100 || req.InstallmentValue < 0 {
+		return Loan{}, errInvalidAgreement
+	}
+	frequency := finance.NormalizeFrequency(req.Frequency)
+	if frequency != finance.FreqDaily && frequency != finance.FreqWeekly && frequency != finance.FreqMonthly {
+		return Loan{}, errInvalidAgreement
+	}
+	reqIndex := ""
+	if req.CorrectionIndex != "" {
+		index, ok := normalizeIndex(req.CorrectionIndex)
+		if !ok {
+			return Loan{}, errInvalidIndex
+		}
+		reqIndex = index
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		l, err := findLoan(ctx, id)
+		if err != nil {
+			return l, err
+		}
+		if l.WriteOff != nil {
+			return l, errLoanWrittenOff
+		}
+		for _, a := range l.Agreements {
+			if a.Status == AgreementActive {
+				return l, errAgreementActive
+			}
+		}
+		ref := today()
+		p, err := buildPayoff(l, ref)
+		if err != nil {
+			return l, err
+		}
+		if len(p.Installments) == 0 {
+			return l, errNothingOverdue
+		}
+		if err := ensureLoanSchedule(&l); err != nil {
+			return l, err
+		}
+
+		correctionIndex := reqIndex
+		if correctionIndex == "" {
+			correctionIndex = l.CorrectionIndex
+		}
+		a := Agreement{
+			ID:                  primitive.NewObjectID().Hex(),
+			CreatedAt:           ref.Format(finance.DateLayout),
+			CreatedBy:           user,
+			Status:              AgreementActive,
+			FrozenCapital:       p.OverdueCapital,
+			FrozenInterest:      p.OverdueInterest,
+			FrozenFine:          p.Fine,
+			FrozenMora:          p.Mora,
+			FrozenCorrection:    p.Correction,
+			CorrectionIndex:     correctionIndex,
+			ChargesDiscountRate: req.ChargesDiscountRate,
+			Frequency:           frequency,
+			Note:                req.Note,
+		}
+		a.Discount = (p.Fine + p.Mora).Percent(req.ChargesDiscountRate)
+		a.Total = p.OverdueTotal - a.Discount
+		if req.InstallmentValue > 0 {
+			a.Total = req.InstallmentValue * money.Cents(req.Installments)
+			if a.Total < a.FrozenCapital {
+				return l, errAgreementValue
+			}
+			// Com parcela digitada, o desconto é o que ficou abaixo do saldo vencido.
+			a.Discount = max(0, p.OverdueTotal-a.Total)
+		}
+		a.Schedule = buildAgreementSchedule(a.Total, a.FrozenCapital, req.Installments, first, frequency, dueDateAdjuster(l.DueDateRule))
+		a.InstallmentValue = a.Schedule[0].ExpectedCapital + a.Schedule[0].ExpectedInterest
+
+		for _, in := range p.Installments {
+			a.CoveredInstallments = append(a.CoveredInstallments, in.Number)
+			for i := range l.Schedule {
+				if l.Schedule[i].Number == in.Number {
+					l.Schedule[i].AgreementID = a.ID
+				}
+			}
+		}
+
+		l.Agreements = append(l.Agreements, a)
+		l.AgreementDate = a.CreatedAt
+		l.History = append(l.History, PaymentRecord{
+			Date:            time.Now().Format(time.RFC3339),
+			Type:            "Acordo",
+			Note:            fmt.Sprintf("ACORDO: saldo vencido de R$ %s renegociado em %dx de R$ %s (desconto de R$ %s em encargos).", p.OverdueTotal, req.Installments, a.InstallmentValue, a.Discount),
+			RegisteredAt:    time.Now().Format(time.RFC3339),
+			OriginalDueDate: l.NextDue,
+			User:            user,
+		})
+		refreshLoanState(&l, ref)
+
+		err = saveLoanVersioned(ctx, &l)
+		if err == errLoanConflict {
+			continue
+		}
+		if err != nil {
+			return l, err
+		}
+		logUserAction("NOVO ACORDO", user, fmt.Sprintf("Contrato %s (%s): parcelas %v renegociadas, total R$ %s em %dx",
+			l.ID, l.Client, a.CoveredInstallments, a.Total, req.Installments))
+		return l, nil
+	}
+	return Loan{}, errLoanConflict
+}
+
+func loanAgreementsHandler(w http.ResponseWriter, r *http.Request, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		l, err := findLoan(ctx, id)
+		if err == errLoanNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Erro ao buscar contrato", http.StatusInternalServerError)
+			return
+		}
+		applyPaymentsToSchedule(&l, today())
+		if l.Agreements == nil {
+			l.Agreements = []Agreement{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(l.Agreements)
+	case http.MethodPost:
+		var req agreementRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		l, err := createAgreement(ctx, id, req, currentUser(r))
+		switch err {
+		case nil:
+		case errLoanNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errInvalidAgreement, errInvalidIndex:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errAgreementActive, errLoanConflict:
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case errNothingOverdue, errAgreementValue, errLoanWrittenOff:
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(l)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// --- Relatório de Recuperação por Acordos ---
+
+type agreementReportRow struct {
+	LoanID      string      `json:"loanId"`
+	Client      string      `json:"client"`
+	AgreementID string      `json:"agreementId"`
+	CreatedAt   string      `json:"createdAt"`
+	Status      string      `json:"status"`
+	Frozen      money.Cents `json:"frozen"`
+	Discount    money.Cents `json:"discount"`
+	Total       money.Cents `json:"total"`
+	Recovered   money.Cents `json:"recovered"`
+	Outstanding money.Cents `json:"outstanding"`
+}
+
+type agreementReport struct {
+	Count       int                  `json:"count"`
+	ByStatus    map[string]int       `json:"byStatus"`
+	Frozen      money.Cents          `json:"frozen"`
+	Discount    money.Cents          `json:"discount"`
+	Total       money.Cents          `json:"total"`
+	Recovered   money.Cents          `json:"recovered"`
+	Outstanding money.Cents          `json:"outstanding"`
+	Agreements  []agreementReportRow `json:"agreements"`
+}
+
+// agreementsReportHandler consolida quanto foi renegociado e recuperado pelos
+// acordos criados no período (?from=&to=, datas AAAA-MM-DD, ambos opcionais).
+// Congelado e recuperado incluem a correção monetária, para fechar com o total.
+func agreementsReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := loanCollection.Find(ctx, bson.M{"agreements.0": bson.M{"$exists": true}})
+	if err != nil {
+		http.Error(w, "Erro ao buscar acordos", http.StatusInternalServerError)
+		return
+	}
+	var loans []Loan
+	cursor.All(ctx, &loans)
+
+	ref := today()
+	rep := agreementReport{ByStatus: map[string]int{}, Agreements: []agreementReportRow{}}
+	for _, l := range loans {
+		applyPaymentsToSchedule(&l, ref)
+		for _, a := range l.Agreements {
+			if (from != "" && a.CreatedAt < from) || (to != "" && a.CreatedAt > to) {
+				continue
+			}
+			row := agreementReportRow{
+				LoanID:      l.ID,
+				Client:      l.Client,
+				AgreementID: a.ID,
+				CreatedAt:   a.CreatedAt,
+				Status:      a.Status,
+				Frozen:      a.FrozenCapital + a.FrozenInterest + a.FrozenFine + a.FrozenMora + a.FrozenCorrection,
+				Discount:    a.Discount,
+				Total:       a.Total,
+			}
+			for _, in := range a.Schedule {
+				row.Recovered += in.PaidCapital + in.PaidInterest + in.PaidFine + in.PaidMora + in.PaidCorrection
+				row.Outstanding += max(0, in.ExpectedCapital+in.ExpectedInterest-in.PaidCapital-in.PaidInterest)
+			}
+
+			rep.Count++
+			rep.ByStatus[strings.ToLower(a.Status)]++
+			rep.Frozen += row.Frozen
+			rep.Discount += row.Discount
+			rep.Total += row.Total
+			rep.Recovered += row.Recovered
+			rep.Outstanding += row.Outstanding
+			rep.Agreements = append(rep.Agreements, row)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rep)
+}
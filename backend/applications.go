@@ -0,0 +1,440 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jules-playground/lms-backend/finance"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Propostas de Empréstimo e Aprovação ---
+
+// Situações de uma proposta. Só a aprovação gera o contrato em "loans".
+const (
+	ApplicationPending  = "Em Análise"
+	ApplicationApproved = "Aprovada"
+	ApplicationRejected = "Rejeitada"
+)
+
+// Perfis que podem decidir propostas. Proposta com pendência nas verificações
+// só pode ser aprovada por ADMIN.
+const (
+	RoleAdmin    = "ADMIN"
+	RoleApprover = "APROVADOR"
+)
+
+// applicationCollection guarda as propostas ("loan_applications").
+var applicationCollection *mongo.Collection
+
+var (
+	errApplicationNotFound = errors.New("Proposta não encontrada")
+	errInvalidApplication  = errors.New("Informe o cliente da proposta")
+	errApplicationDecided  = errors.New("Proposta já foi decidida")
+	errDecisionReason      = errors.New("Informe o motivo da decisão")
+	errNotApprover         = errors.New("Usuário sem perfil de aprovador")
+	errApplicationChecks   = errors.New("Proposta com pendências nas verificações só pode ser aprovada por um Administrador")
+	errApprovalRequired    = errors.New("Contratos novos devem passar por proposta e aprovação (/api/loan-applications)")
+)
+
+// ApplicationCheck é o resultado de uma verificação automática da proposta.
+type ApplicationCheck struct {
+	Name   string `json:"name" bson:"name"`
+	Passed bool   `json:"passed" bson:"passed"`
+	Detail string `json:"detail,omitempty" bson:"detail,omitempty"`
+}
+
+// ApplicationEvent registra cada mudança de situação da proposta.
+type ApplicationEvent struct {
+	Date   string `json:"date" bson:"date"`
+	User   string `json:"user" bson:"user"`
+	Status string `json:"status" bson:"status"`
+	Note   string `json:"note,omitempty" bson:"note,omitempty"`
+}
+
+// LoanApplication é uma proposta de empréstimo. Loan guarda os dados como
+// enviados: o contrato é recalculado na aprovação, com a data do dia.
+type LoanApplication struct {
+	ID             string             `json:"id" bson:"id"`
+	Status         string             `json:"status" bson:"status"`
+	Loan           Loan               `json:"loan" bson:"loan"`
+	Simulation     Simulation         `json:"simulation" bson:"simulation"` // cotação na data da proposta
+	Checks         []ApplicationCheck `json:"checks" bson:"checks"`
+	Checklist      *ChecklistTemplate `json:"checklist,omitempty" bson:"checklist,omitempty"` // modelo vigente para a proposta
+	Score          *ClientScore       `json:"score,omitempty" bson:"score,omitempty"`         // score do cliente na análise
+	CreatedAt      string             `json:"createdAt" bson:"createdAt"`
+	CreatedBy      string             `json:"createdBy" bson:"createdBy"`
+	DecidedAt      string             `json:"decidedAt,omitempty" bson:"decidedAt,omitempty"`
+	DecidedBy      string             `json:"decidedBy,omitempty" bson:"decidedBy,omitempty"`
+	DecisionReason string             `json:"decisionReason,omitempty" bson:"decisionReason,omitempty"`
+	LoanID         string             `json:"loanId,omitempty" bson:"loanId,omitempty"` // contrato gerado na aprovação
+	Events         []ApplicationEvent `json:"events" bson:"events"`
+}
+
+// checksPassed informa se todas as verificações passaram.
+func checksPassed(checks []ApplicationCheck) bool {
+	for _, c := range checks {
+		if !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// runApplicationChecks confere lista negra (cliente e garantidor), contratos em
+// atraso ou baixados do cliente, documentos no cadastro e limite de crédito.
+func runApplicationChecks(ctx context.Context, l Loan) []ApplicationCheck {
+	var client Client
+	clientErr := clientCollection.FindOne(ctx, bson.M{"name": l.Client}).Decode(&client)
+
+	// Risco Alto reprova a verificação; Médio e Baixo só ficam no detalhe.
+	black := ApplicationCheck{Name: "Lista negra", Passed: true}
+	hits, err := blacklistHits(ctx, screenedDoc{"Cliente", "client", client.CPF}, screenedDoc{"Avalista", "guarantorCPF", l.GuarantorCPF})
+	if err != nil {
+		black.Passed, black.Detail = false, "Erro ao consultar a lista negra"
+	}
+	var details []string
+	for _, h := range hits {
+		if h.Blocked {
+			black.Passed = false
+		}
+		details = append(details, fmt.Sprintf("%s %s listado (entrada %s, risco %s): %s", h.Role, h.CPF, h.EntryID, h.RiskLevel, h.Reason))
+	}
+	if len(details) > 0 {
+		black.Detail = strings.Join(details, "; ")
+	}
+
+	overdue := ApplicationCheck{Name: "Contratos em atraso", Passed: true}
+	n, err := loanCollection.CountDocuments(ctx, bson.M{"client": l.Client, "status": bson.M{"$in": []string{"Atrasado", LoanWrittenOff}}})
+	if err != nil {
+		overdue.Passed, overdue.Detail = false, "Erro ao consultar contratos do cliente"
+	} else if n > 0 {
+		overdue.Passed = false
+		overdue.Detail = fmt.Sprintf("%d contrato(s) em atraso ou baixado(s) como perda", n)
+	}
+
+	docs := ApplicationCheck{Name: "Documentos do cliente", Passed: true}
+	switch {
+	case clientErr != nil:
+		docs.Passed, docs.Detail = false, "Cliente não cadastrado"
+	case len(client.Documents) == 0:
+		docs.Passed, docs.Detail = false, "Nenhum documento anexado ao cadastro"
+	}
+	limit := ApplicationCheck{Name: "Limite de crédito", Passed: true}
+	if clientErr == nil && client.CreditLimit > 0 {
+		if e, err := clientExposure(ctx, client); err != nil {
+			limit.Passed, limit.Detail = false, "Erro ao calcular a exposição do cliente"
+		} else if e.Exposure+l.Amount > client.CreditLimit {
+			limit.Passed = false
+			limit.Detail = fmt.Sprintf("Exposição de R$ %s mais a proposta passa do limite de R$ %s", e.Exposure, client.CreditLimit)
+		}
+	}
+	return []ApplicationCheck{black, overdue, docs, limit}
+}
+
+// applicationScore recalcula o score do cliente da proposta; nil se o cliente
+// não estiver cadastrado.
+func applicationScore(ctx context.Context, l Loan) *ClientScore {
+	c, err := findClientByName(ctx, l.Client)
+	if err != nil {
+		return nil
+	}
+	s, err := refreshClientScore(ctx, c)
+	if err != nil {
+		return nil
+	}
+	return &s
+}
+
+// loanApprovalRequired diz se contratos só nascem de proposta aprovada. Sem
+// configuração gravada fica desligada, já que a tela de novo contrato ainda
+// usa o POST /api/loans direto.
+func (s SystemSettings) loanApprovalRequired() bool {
+	return s.RequireLoanApproval != nil && *s.RequireLoanApproval
+}
+
+// createApplication valida e cota a proposta e grava as verificações.
+func createApplication(ctx context.Context, l Loan, user string) (LoanApplication, error) {
+	if strings.TrimSpace(l.Client) == "" {
+		return LoanApplication{}, errInvalidApplication
+	}
+	sim, err := simulateNewLoan(ctx, &l)
+	if err != nil {
+		return LoanApplication{}, err
+	}
+	// Na proposta o risco Alto não impede o registro: reprova a verificação e a
+	// aprovação passa a exigir um administrador.
+	if err := screenLoanBlacklist(ctx, &l, "Proposta", user); err != nil {
+		if _, blocked := err.(blacklistBlockedError); !blocked {
+			return LoanApplication{}, err
+		}
+	}
+	now := time.Now().Format(time.RFC3339)
+	a := LoanApplication{
+		ID:         primitive.NewObjectID().Hex(),
+		Status:     ApplicationPending,
+		Loan:       l,
+		Simulation: sim,
+		Checks:     runApplicationChecks(ctx, l),
+		Checklist:  selectChecklist(loadSettings(ctx).System.ChecklistTemplates, l),
+		Score:      applicationScore(ctx, l),
+		CreatedAt:  now,
+		CreatedBy:  user,
+		Events:     []ApplicationEvent{{Date: now, User: user, Status: ApplicationPending, Note: "Proposta registrada"}},
+	}
+	if _, err := applicationCollection.InsertOne(ctx, a); err != nil {
+		return a, err
+	}
+	logUserAction("PROPOSTA REGISTRADA", user, fmt.Sprintf("Proposta %s (%s): R$ %s em %dx. Verificações: %s",
+		a.ID, l.Client, l.Amount, l.Installments, checksSummary(a.Checks)))
+	return a, nil
+}
+
+// checksSummary resume as verificações para o log de auditoria.
+func checksSummary(checks []ApplicationCheck) string {
+	var failed []string
+	for _, c := range checks {
+		if !c.Passed {
+			failed = append(failed, c.Name)
+		}
+	}
+	if len(failed) == 0 {
+		return "sem pendências"
+	}
+	return "pendências em " + strings.Join(failed, ", ")
+}
+
+func findApplication(ctx context.Context, id string) (LoanApplication, error) {
+	var a LoanApplication
+	err := applicationCollection.FindOne(ctx, bson.M{"id": id}).Decode(&a)
+	if err == mongo.ErrNoDocuments {
+		return a, errApplicationNotFound
+	}
+	return a, err
+}
+
+type decisionRequest struct {
+	Reason    string   `json:"reason"`
+	Checklist []string `json:"checklist"` // itens marcados (ID ou texto), exigidos na aprovação
+}
+
+// decideApplication aprova ou rejeita a proposta. Na aprovação as verificações
+// são refeitas, o checklist do modelo vigente precisa ter todos os itens
+// obrigatórios marcados e o contrato é gerado com a cópia dessa versão; a troca de situação só acontece se a
+// proposta ainda estiver em análise, então duas decisões simultâneas não geram
+// dois contratos.
+func decideApplication(ctx context.Context, id string, approve bool, req decisionRequest, user string) (LoanApplication, error) {
+	role := userRole(ctx, user)
+	if role != RoleAdmin && role != RoleApprover {
+		return LoanApplication{}, errNotApprover
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return LoanApplication{}, errDecisionReason
+	}
+	a, err := findApplication(ctx, id)
+	if err != nil {
+		return a, err
+	}
+	if a.Status != ApplicationPending {
+		return a, errApplicationDecided
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	status := ApplicationRejected
+	set := bson.M{"decidedAt": now, "decidedBy": user, "decisionReason": req.Reason}
+	var l Loan
+	if approve {
+		status = ApplicationApproved
+		a.Checks = runApplicationChecks(ctx, a.Loan)
+		a.Score = applicationScore(ctx, a.Loan)
+		if !checksPassed(a.Checks) && role != RoleAdmin {
+			applicationCollection.UpdateOne(ctx, bson.M{"id": a.ID}, bson.M{"$set": bson.M{"checks": a.Checks}})
+			return a, errApplicationChecks
+		}
+		a.Checklist = selectChecklist(loadSettings(ctx).System.ChecklistTemplates, a.Loan)
+		l = a.Loan
+		if err := applyChecklist(a.Checklist, &l, req.Checklist, user); err != nil {
+			return a, err
+		}
+		l.ID = primitive.NewObjectID().Hex()
+		l.ApplicationID = a.ID
+		l.Status = "Em Dia"
+		l.History = []PaymentRecord{}
+		if l.Justification == "" {
+			l.Justification = req.Reason
+		}
+		if err := prepareNewLoan(ctx, &l); err != nil {
+			return a, err
+		}
+		// A aprovação do ADMIN com pendência é a liberação acima do limite.
+		if role == RoleAdmin && l.CreditLimitOverride == nil {
+			l.CreditLimitOverride = &CreditLimitOverride{Justification: req.Reason}
+		}
+		if err := checkCreditLimit(ctx, &l, user); err != nil {
+			return a, err
+		}
+		set["loanId"] = l.ID
+		set["checks"] = a.Checks
+		set["checklist"] = a.Checklist
+		set["score"] = a.Score
+	}
+	set["status"] = status
+	event := ApplicationEvent{Date: now, User: user, Status: status, Note: req.Reason}
+
+	res, err := applicationCollection.UpdateOne(ctx,
+		bson.M{"id": a.ID, "status": ApplicationPending},
+		bson.M{"$set": set, "$push": bson.M{"events": event}})
+	if err != nil {
+		return a, err
+	}
+	if res.MatchedCount == 0 {
+		return a, errApplicationDecided
+	}
+	if approve {
+		l.refreshAffiliateFee()
+		if _, err := loanCollection.InsertOne(ctx, l); err != nil {
+			// Devolve a proposta para análise: o contrato não foi gravado.
+			applicationCollection.UpdateOne(ctx, bson.M{"id": a.ID}, bson.M{
+				"$set":   bson.M{"status": ApplicationPending},
+				"$unset": bson.M{"decidedAt": "", "decidedBy": "", "decisionReason": "", "loanId": ""},
+				"$push":  bson.M{"events": ApplicationEvent{Date: time.Now().Format(time.RFC3339), User: "Sistema", Status: ApplicationPending, Note: "Falha ao gravar o contrato aprovado"}},
+			})
+			return a, err
+		}
+		logUserAction("PROPOSTA APROVADA", user, fmt.Sprintf("Proposta %s (%s) aprovada, contrato %s: R$ %s em %dx. Verificações: %s. Motivo: %s",
+			a.ID, l.Client, l.ID, l.Amount, l.Installments, checksSummary(a.Checks), req.Reason))
+	} else {
+		logUserAction("PROPOSTA REJEITADA", user, fmt.Sprintf("Proposta %s (%s) rejeitada. Motivo: %s", a.ID, a.Loan.Client, req.Reason))
+	}
+	return findApplication(ctx, a.ID)
+}
+
+func writeApplicationError(w http.ResponseWriter, err error) {
+	var incomplete checklistIncompleteError
+	var overLimit creditLimitError
+	if errors.As(err, &incomplete) || errors.As(err, &overLimit) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	switch err {
+	case errApplicationNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errInvalidApplication, errDecisionReason, errInvalidPaymentDate, errInvalidDueDateRule, errInvalidIndex,
+		finance.ErrInvalidAmount, finance.ErrInvalidInstallments, finance.ErrInvalidRate, finance.ErrInvalidType, finance.ErrInvalidFrequency,
+		finance.ErrInvalidGraceDays, finance.ErrInvalidGraceRule:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errNotApprover:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errApplicationDecided:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errApplicationChecks:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// applicationsHandler lista (?status=) e registra propostas.
+func applicationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		filter := bson.M{}
+		if s := r.URL.Query().Get("status"); s != "" {
+			filter["status"] = s
+		}
+		cursor, err := applicationCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"createdAt": -1}))
+		if err != nil {
+			http.Error(w, "Erro ao buscar propostas", http.StatusInternalServerError)
+			return
+		}
+		var res []LoanApplication
+		cursor.All(ctx, &res)
+		if res == nil {
+			res = []LoanApplication{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	case http.MethodPost:
+		var l Loan
+		if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		if fe := l.normalizeDocuments(); len(fe) > 0 {
+			writeFieldErrors(w, http.StatusBadRequest, fe)
+			return
+		}
+		if fe, err := linkAffiliate(ctx, &l); err != nil {
+			http.Error(w, "Erro ao buscar afiliado", http.StatusInternalServerError)
+			return
+		} else if len(fe) > 0 {
+			writeFieldErrors(w, http.StatusBadRequest, fe)
+			return
+		}
+		a, err := createApplication(ctx, l, currentUser(r))
+		if err != nil {
+			writeApplicationError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(a)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// applicationDetailHandler atende /api/loan-applications/{id}[/approve|/reject].
+func applicationDetailHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/loan-applications/"), "/"), "/")
+	id := parts[0]
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		a, err := findApplication(ctx, id)
+		if err != nil {
+			writeApplicationError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(a)
+		return
+	}
+	if len(parts) != 2 || (parts[1] != "approve" && parts[1] != "reject") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req decisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	a, err := decideApplication(ctx, id, parts[1] == "approve", req, currentUser(r))
+	if err != nil {
+		writeApplicationError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
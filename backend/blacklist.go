@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jules-playground/lms-backend/document"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// --- Consulta à Lista Negra ---
+
+// findBlacklisted devolve a primeira entrada da lista negra com algum dos CPFs
+// informados, com ou sem máscara. CPFs vazios são ignorados.
+func findBlacklisted(ctx context.Context, cpfs ...string) (*BlacklistEntry, error) {
+	want := bson.A{}
+	for _, c := range cpfs {
+		if d := document.Digits(c); d != "" {
+			want = append(want, d)
+		}
+	}
+	if len(want) == 0 {
+		return nil, nil
+	}
+	var entry BlacklistEntry
+	err := blacklistCollection.FindOne(ctx, bson.M{"cpfDigits": bson.M{"$in": want}}).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// --- Triagem na Lista Negra ---
+
+// Níveis de risco das entradas. Risco Alto (ou não informado) bloqueia o
+// cadastro; Médio e Baixo só geram alerta.
+const (
+	RiskHigh   = "Alto"
+	RiskMedium = "Médio"
+	RiskLow    = "Baixo"
+)
+
+// BlacklistHit é um documento do cadastro encontrado na lista negra.
+type BlacklistHit struct {
+	EntryID   string `json:"entryId" bson:"entryId"`
+	Role      string `json:"role" bson:"role"` // Cliente ou Avalista
+	Field     string `json:"field" bson:"field"`
+	Name      string `json:"name" bson:"name"`
+	CPF       string `json:"cpf" bson:"cpf"`
+	Reason    string `json:"reason" bson:"reason"`
+	RiskLevel string `json:"riskLevel" bson:"riskLevel"`
+	Blocked   bool   `json:"blocked" bson:"blocked"`
+}
+
+// screenedDoc é um documento a conferir, com o papel e o campo do formulário.
+type screenedDoc struct {
+	Role, Field, CPF string
+}
+
+// blacklistBlockedError é devolvido quando algum documento tem entrada de
+// risco Alto.
+type blacklistBlockedError struct {
+	Hits []BlacklistHit
+}
+
+func (e blacklistBlockedError) Error() string {
+	var parts []string
+	for _, h := range e.Hits {
+		if h.Blocked {
+			parts = append(parts, fmt.Sprintf("%s %s na lista negra (risco %s): %s", h.Role, h.CPF, h.RiskLevel, h.Reason))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// fields devolve o erro de cada campo bloqueado, para o formulário.
+func (e blacklistBlockedError) fields() fieldErrors {
+	fe := fieldErrors{}
+	for _, h := range e.Hits {
+		if h.Blocked {
+			fe[h.Field] = fmt.Sprintf("%s na lista negra (risco %s): %s", h.CPF, h.RiskLevel, h.Reason)
+		}
+	}
+	return fe
+}
+
+// blocksRisk diz se o nível de risco bloqueia. Só Médio e Baixo liberam.
+func blocksRisk(risk string) bool {
+	switch strings.ToLower(strings.TrimSpace(risk)) {
+	case "médio", "medio", "baixo":
+		return false
+	}
+	return true
+}
+
+// blacklistHits devolve todas as entradas da lista negra que casam com os
+// documentos informados. Documentos vazios são ignorados.
+func blacklistHits(ctx context.Context, docs ...screenedDoc) ([]BlacklistHit, error) {
+	byDigits := map[string][]screenedDoc{}
+	want := bson.A{}
+	for _, d := range docs {
+		digits := document.Digits(d.CPF)
+		if digits == "" {
+			continue
+		}
+		if len(byDigits[digits]) == 0 {
+			want = append(want, digits)
+		}
+		byDigits[digits] = append(byDigits[digits], d)
+	}
+	if len(want) == 0 {
+		return nil, nil
+	}
+	cursor, err := blacklistCollection.Find(ctx, bson.M{"cpfDigits": bson.M{"$in": want}})
+	if err != nil {
+		return nil, err
+	}
+	var entries []BlacklistEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	var hits []BlacklistHit
+	for _, e := range entries {
+		risk := e.Risk
+		if strings.TrimSpace(risk) == "" {
+			risk = RiskHigh
+		}
+		for _, d := range byDigits[e.CPFDigits] {
+			hits = append(hits, BlacklistHit{
+				EntryID: e.ID, Role: d.Role, Field: d.Field, Name: e.Name, CPF: e.CPF,
+				Reason: e.Reason, RiskLevel: risk, Blocked: blocksRisk(risk),
+			})
+		}
+	}
+	return hits, nil
+}
+
+// screenBlacklist confere os documentos na lista negra e registra cada ocorrência
+// no log com o ID da entrada. Devolve as ocorrências e, se alguma bloquear,
+// um blacklistBlockedError.
+func screenBlacklist(ctx context.Context, subject, user string, docs ...screenedDoc) ([]BlacklistHit, error) {
+	hits, err := blacklistHits(ctx, docs...)
+	if err != nil {
+		return nil, err
+	}
+	blocked := false
+	for _, h := range hits {
+		action := "LISTA NEGRA - ALERTA"
+		if h.Blocked {
+			action, blocked = "LISTA NEGRA - BLOQUEIO", true
+		}
+		logUserAction(action, user, fmt.Sprintf("%s: %s %s consta na lista negra (entrada %s, %s, risco %s): %s",
+			subject, h.Role, h.CPF, h.EntryID, h.Name, h.RiskLevel, h.Reason))
+	}
+	if blocked {
+		return hits, blacklistBlockedError{Hits: hits}
+	}
+	return hits, nil
+}
+
+// screenLoanBlacklist confere o CPF do cliente e o do avalista do contrato e
+// guarda as ocorrências no próprio contrato.
+func screenLoanBlacklist(ctx context.Context, l *Loan, subject, user string) error {
+	var cpf string
+	if c, err := findClientByName(ctx, l.Client); err == nil {
+		cpf = c.CPF
+	} else if err != mongo.ErrNoDocuments {
+		return err
+	}
+	hits, err := screenBlacklist(ctx, fmt.Sprintf("%s de %s", subject, l.Client), user,
+		screenedDoc{"Cliente", "client", cpf}, screenedDoc{"Avalista", "guarantorCPF", l.GuarantorCPF})
+	l.BlacklistHits = hits
+	return err
+}
+
+// --- Cadastro da Lista Negra ---
+
+// normalizeRisk aceita o nível de risco sem acento ou caixa; vazio é Alto.
+func normalizeRisk(risk string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(risk)) {
+	case "", "alto":
+		return RiskHigh, true
+	case "médio", "medio":
+		return RiskMedium, true
+	case "baixo":
+		return RiskLow, true
+	}
+	return risk, false
+}
+
+// validateBlacklistEntry normaliza a entrada e devolve os erros por campo.
+func validateBlacklistEntry(e *BlacklistEntry) fieldErrors {
+	e.Name, e.Reason = strings.TrimSpace(e.Name), strings.TrimSpace(e.Reason)
+	fe := e.normalizeDocuments()
+	if e.Name == "" {
+		fe["name"] = "Informe o nome"
+	}
+	if e.CPF == "" {
+		fe["cpf"] = "Informe o CPF ou CNPJ"
+	}
+	if e.Reason == "" {
+		fe["reason"] = "Informe o motivo"
+	}
+	risk, ok := normalizeRisk(e.Risk)
+	if !ok {
+		fe["riskLevel"] = "Risco deve ser Alto, Médio ou Baixo"
+	}
+	e.Risk = risk
+	if e.Date == "" {
+		e.Date = today().Format("02/01/2006")
+	}
+	return fe
+}
+
+// checkBlacklistDuplicate impede duas entradas para o mesmo documento.
+func checkBlacklistDuplicate(ctx context.Context, e BlacklistEntry) (fieldErrors, error) {
+	var other BlacklistEntry
+	err := blacklistCollection.FindOne(ctx, bson.M{"cpfDigits": e.CPFDigits, "id": bson.M{"$ne": e.ID}}).Decode(&other)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fieldErrors{"cpf": fmt.Sprintf("Documento já está na lista negra (entrada %s, %s)", other.ID, other.Name)}, nil
+}
+
+// blacklistHandler atende GET (lista) e POST (inclusão) em /api/blacklist.
+func blacklistHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	switch r.Method {
+	case http.MethodGet:
+		cursor, err := blacklistCollection.Find(ctx, bson.M{})
+		if err != nil {
+			http.Error(w, "Erro ao buscar a lista negra", http.StatusInternalServerError)
+			return
+		}
+		var res []BlacklistEntry
+		cursor.All(ctx, &res)
+		if res == nil {
+			res = []BlacklistEntry{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	case http.MethodPost:
+		var e BlacklistEntry
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		e.ID = primitive.NewObjectID().Hex()
+		if !saveBlacklistEntry(ctx, w, &e, true) {
+			return
+		}
+		logUserAction("LISTA NEGRA - INCLUSÃO", currentUser(r), fmt.Sprintf("Entrada %s: %s (%s), risco %s - %s", e.ID, e.CPF, e.Name, e.Risk, e.Reason))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(e)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// saveBlacklistEntry valida e grava a entrada, respondendo o erro quando falha.
+func saveBlacklistEntry(ctx context.Context, w http.ResponseWriter, e *BlacklistEntry, insert bool) bool {
+	if fe := validateBlacklistEntry(e); len(fe) > 0 {
+		writeFieldErrors(w, http.StatusBadRequest, fe)
+		return false
+	}
+	fe, err := checkBlacklistDuplicate(ctx, *e)
+	if err == nil && len(fe) > 0 {
+		writeFieldErrors(w, http.StatusConflict, fe)
+		return false
+	}
+	if err == nil {
+		if insert {
+			_, err = blacklistCollection.InsertOne(ctx, e)
+		} else {
+			_, err = blacklistCollection.ReplaceOne(ctx, bson.M{"id": e.ID}, e)
+		}
+	}
+	if err != nil {
+		http.Error(w, "Erro ao salvar na lista negra", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// blacklistUpdateHandler atende GET, PUT e DELETE em /api/blacklist/{id}.
+func blacklistUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/blacklist/")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var current BlacklistEntry
+	if err := blacklistCollection.FindOne(ctx, bson.M{"id": id}).Decode(&current); err == mongo.ErrNoDocuments {
+		http.Error(w, "Entrada não encontrada", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Erro ao buscar a lista negra", http.StatusInternalServerError)
+		return
+	}
+	user := currentUser(r)
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(current)
+	case http.MethodPut:
+		var e BlacklistEntry
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		e.ID = id
+		if e.Date == "" {
+			e.Date = current.Date
+		}
+		if !saveBlacklistEntry(ctx, w, &e, false) {
+			return
+		}
+		logUserAction("LISTA NEGRA - ALTERAÇÃO", user, fmt.Sprintf("Entrada %s: %s (%s), risco %s → %s - %s", id, e.CPF, e.Name, current.Risk, e.Risk, e.Reason))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(e)
+	case http.MethodDelete:
+		if _, err := blacklistCollection.DeleteOne(ctx, bson.M{"id": id}); err != nil {
+			http.Error(w, "Erro ao remover da lista negra", http.StatusInternalServerError)
+			return
+		}
+		logUserAction("LISTA NEGRA - REMOÇÃO", user, fmt.Sprintf("Entrada %s: %s (%s) removida", id, current.CPF, current.Name))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
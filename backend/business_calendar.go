@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jules-playground/lms-backend/calendar"
+	"github.com/jules-playground/lms-backend/finance"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Calendário de Dias Úteis ---
+
+// defaultDueDateRule vale para contratos novos que não informam a regra.
+// Contratos antigos (sem regra gravada) mantêm as datas que já tinham.
+const defaultDueDateRule = calendar.RuleNextBusinessDay
+
+var holidayCalendar atomic.Pointer[calendar.Calendar]
+
+// businessCalendar devolve o calendário com os feriados locais das configurações.
+func businessCalendar() *calendar.Calendar {
+	if c := holidayCalendar.Load(); c != nil {
+		return c
+	}
+	return calendar.New(nil)
+}
+
+// reloadBusinessCalendar relê os feriados locais; chamado na subida e sempre
+// que as configurações mudam.
+func reloadBusinessCalendar(ctx context.Context) {
+	s := loadSettings(ctx)
+	holidayCalendar.Store(calendar.New(s.System.LocalHolidays))
+}
+
+// normalizeDueDateRule valida a regra de vencimento; vazio vira o padrão.
+func normalizeDueDateRule(rule string) (string, bool) {
+	switch rule {
+	case "":
+		return defaultDueDateRule, true
+	case calendar.RuleKeep, calendar.RuleNextBusinessDay:
+		return rule, true
+	}
+	return "", false
+}
+
+// dueDateAdjuster devolve o ajuste de vencimento da regra do contrato (nil = sem ajuste).
+func dueDateAdjuster(rule string) func(time.Time) time.Time {
+	return businessCalendar().Adjuster(rule)
+}
+
+// isOverdue diz se a parcela está vencida na data de referência. Vencimento em
+// sábado, domingo ou feriado pode ser pago no dia útil seguinte sem atraso,
+// mesmo em contratos antigos cujas datas não foram ajustadas.
+func isOverdue(dueDate string, ref time.Time) bool {
+	refDate := ref.Format(finance.DateLayout)
+	if dueDate >= refDate {
+		return false
+	}
+	due, err := parseLoanDate(dueDate)
+	if err != nil {
+		return true
+	}
+	return businessCalendar().NextBusinessDay(due).Format(finance.DateLayout) < refDate
+}
+
+// chargeableDaysLate são os dias de atraso para multa e mora: zero enquanto a
+// parcela não estiver vencida pelo calendário, senão contados desde o vencimento original.
+func chargeableDaysLate(dueDate string, ref time.Time) int {
+	if !isOverdue(dueDate, ref) {
+		return 0
+	}
+	return daysLate(dueDate, ref)
+}
+
+var (
+	errInvalidHoliday     = errors.New("Feriado inválido: informe date (AAAA-MM-DD ou MM-DD) e name")
+	errInvalidDueDateRule = errors.New("Regra de vencimento inválida: use MANTER ou PROXIMO_UTIL")
+)
+
+func validHolidayDate(date string) bool {
+	switch len(date) {
+	case 10:
+		_, err := time.Parse(finance.DateLayout, date)
+		return err == nil
+	case 5:
+		_, err := time.Parse("01-02", date)
+		return err == nil || date == "02-29"
+	}
+	return false
+}
+
+// holidaysHandler lista os feriados do ano (nacionais e locais) e, para
+// administradores, substitui a lista de feriados locais.
+func holidaysHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		year := today().Year()
+		if y := r.URL.Query().Get("year"); y != "" {
+			n, err := strconv.Atoi(y)
+			if err != nil || n < 1900 || n > 2200 {
+				http.Error(w, "Ano inválido", http.StatusBadRequest)
+				return
+			}
+			year = n
+		}
+		s := loadSettings(ctx)
+		local := s.System.LocalHolidays
+		if local == nil {
+			local = []calendar.Holiday{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"year":     year,
+			"holidays": calendar.New(local).Holidays(year),
+			"local":    local,
+		})
+	case http.MethodPut:
+		user := currentUser(r)
+		if !isAdminUser(ctx, user) {
+			logAction("ACESSO NEGADO ADMIN", user)
+			http.Error(w, "Acesso restrito a Administradores.", http.StatusForbidden)
+			return
+		}
+		var local []calendar.Holiday
+		if err := json.NewDecoder(r.Body).Decode(&local); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		for _, h := range local {
+			if h.Name == "" || !validHolidayDate(h.Date) {
+				http.Error(w, errInvalidHoliday.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if local == nil {
+			local = []calendar.Holiday{}
+		}
+		opts := options.Update().SetUpsert(true)
+		if _, err := settingsCollection.UpdateOne(ctx, bson.M{}, bson.M{"$set": bson.M{"system.localHolidays": local}}, opts); err != nil {
+			http.Error(w, "Erro ao salvar feriados", http.StatusInternalServerError)
+			return
+		}
+		reloadBusinessCalendar(ctx)
+		logUserAction("FERIADOS LOCAIS", user, strconv.Itoa(len(local))+" feriado(s) local(is) cadastrado(s)")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(local)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,146 @@
+// Package calendar conhece os feriados nacionais brasileiros (fixos e móveis) e
+// os feriados locais cadastrados, e ajusta vencimentos para dias úteis.
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Regras de ajuste de vencimento de um contrato.
+const (
+	RuleKeep            = "MANTER"       // vencimento cai na data calculada, mesmo em feriado
+	RuleNextBusinessDay = "PROXIMO_UTIL" // vencimento em dia não útil vai para o próximo dia útil
+)
+
+const dateLayout = "2006-01-02"
+
+// Holiday é um feriado. Date no formato "2006-01-02" vale só naquele ano;
+// no formato "01-02" (mês-dia) repete todo ano.
+type Holiday struct {
+	Date string `json:"date" bson:"date"`
+	Name string `json:"name" bson:"name"`
+}
+
+// Calendar responde se uma data é dia útil.
+type Calendar struct {
+	local map[string]string
+}
+
+// New cria um calendário com os feriados nacionais mais os feriados locais informados.
+func New(local []Holiday) *Calendar {
+	c := &Calendar{local: map[string]string{}}
+	for _, h := range local {
+		c.local[h.Date] = h.Name
+	}
+	return c
+}
+
+// Easter devolve o domingo de Páscoa do ano (algoritmo de Meeus/Jones/Butcher).
+func Easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// NationalHolidays lista os feriados nacionais do ano, incluindo os móveis
+// (Carnaval, Sexta-feira Santa e Corpus Christi, em que não há expediente bancário).
+func NationalHolidays(year int) []Holiday {
+	fixed := func(m time.Month, d int, name string) Holiday {
+		return Holiday{Date: time.Date(year, m, d, 0, 0, 0, 0, time.UTC).Format(dateLayout), Name: name}
+	}
+	easter := Easter(year)
+	moving := func(offset int, name string) Holiday {
+		return Holiday{Date: easter.AddDate(0, 0, offset).Format(dateLayout), Name: name}
+	}
+	holidays := []Holiday{
+		fixed(time.January, 1, "Confraternização Universal"),
+		moving(-48, "Carnaval"),
+		moving(-47, "Carnaval"),
+		moving(-2, "Sexta-feira Santa"),
+		fixed(time.April, 21, "Tiradentes"),
+		fixed(time.May, 1, "Dia do Trabalho"),
+		moving(60, "Corpus Christi"),
+		fixed(time.September, 7, "Independência do Brasil"),
+		fixed(time.October, 12, "Nossa Senhora Aparecida"),
+		fixed(time.November, 2, "Finados"),
+		fixed(time.November, 15, "Proclamação da República"),
+	}
+	// Consciência Negra é feriado nacional desde a Lei 14.759/2023.
+	if year >= 2024 {
+		holidays = append(holidays, fixed(time.November, 20, "Dia Nacional de Zumbi e da Consciência Negra"))
+	}
+	return append(holidays, fixed(time.December, 25, "Natal"))
+}
+
+// Holidays lista os feriados nacionais e locais do ano.
+func (c *Calendar) Holidays(year int) []Holiday {
+	out := NationalHolidays(year)
+	prefix := fmt.Sprintf("%04d-", year)
+	for date, name := range c.local {
+		if len(date) == 5 {
+			out = append(out, Holiday{Date: prefix + date, Name: name})
+		} else if strings.HasPrefix(date, prefix) {
+			out = append(out, Holiday{Date: date, Name: name})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date < out[j].Date })
+	return out
+}
+
+// HolidayName devolve o nome do feriado na data, se houver.
+func (c *Calendar) HolidayName(t time.Time) (string, bool) {
+	date := t.Format(dateLayout)
+	if name, ok := c.local[date]; ok {
+		return name, true
+	}
+	if name, ok := c.local[date[5:]]; ok {
+		return name, true
+	}
+	for _, h := range NationalHolidays(t.Year()) {
+		if h.Date == date {
+			return h.Name, true
+		}
+	}
+	return "", false
+}
+
+// IsBusinessDay é falso para sábados, domingos e feriados.
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	if wd := t.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	_, holiday := c.HolidayName(t)
+	return !holiday
+}
+
+// NextBusinessDay devolve a própria data se for dia útil, senão o próximo dia útil.
+func (c *Calendar) NextBusinessDay(t time.Time) time.Time {
+	for !c.IsBusinessDay(t) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// Adjuster devolve a função de ajuste de vencimento da regra, ou nil quando a
+// regra mantém a data calculada.
+func (c *Calendar) Adjuster(rule string) func(time.Time) time.Time {
+	if rule != RuleNextBusinessDay {
+		return nil
+	}
+	return c.NextBusinessDay
+}
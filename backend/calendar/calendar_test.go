@@ -0,0 +1,70 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse(dateLayout, s)
+	return t
+}
+
+func TestEaster(t *testing.T) {
+	tests := map[int]string{
+		2024: "2024-03-31",
+		2025: "2025-04-20",
+		2026: "2026-04-05",
+		2027: "2027-03-28",
+		2038: "2038-04-25",
+	}
+	for year, want := range tests {
+		if got := Easter(year).Format(dateLayout); got != want {
+			t.Errorf("Easter(%d) = %s, esperado %s", year, got, want)
+		}
+	}
+}
+
+func TestIsBusinessDay(t *testing.T) {
+	c := New([]Holiday{{Date: "01-25", Name: "Aniversário de São Paulo"}, {Date: "2026-06-12", Name: "Ponto facultativo"}})
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"2026-04-06", true},  // segunda depois da Páscoa
+		{"2026-02-16", false}, // segunda de Carnaval
+		{"2026-02-17", false}, // terça de Carnaval
+		{"2026-04-03", false}, // Sexta-feira Santa
+		{"2026-06-04", false}, // Corpus Christi
+		{"2026-11-20", false}, // Consciência Negra
+		{"2023-11-20", true},  // Consciência Negra antes da lei nacional
+		{"2026-03-08", false}, // domingo
+		{"2026-01-26", true},  // segunda comum
+		{"2027-01-25", false}, // feriado local recorrente
+		{"2026-06-12", false}, // feriado local do ano
+		{"2027-06-11", true},  // mesmo feriado local não repete
+	}
+	for _, tt := range tests {
+		if got := c.IsBusinessDay(date(tt.date)); got != tt.want {
+			t.Errorf("IsBusinessDay(%s) = %v, esperado %v", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestNextBusinessDay(t *testing.T) {
+	c := New(nil)
+	tests := []struct{ date, want string }{
+		{"2026-04-03", "2026-04-06"}, // Sexta-feira Santa -> segunda
+		{"2026-02-14", "2026-02-18"}, // sábado de Carnaval -> quarta de cinzas
+		{"2026-12-25", "2026-12-28"}, // Natal numa sexta
+		{"2026-03-10", "2026-03-10"}, // já é dia útil
+	}
+	for _, tt := range tests {
+		if got := c.NextBusinessDay(date(tt.date)).Format(dateLayout); got != tt.want {
+			t.Errorf("NextBusinessDay(%s) = %s, esperado %s", tt.date, got, tt.want)
+		}
+	}
+	if c.Adjuster(RuleKeep) != nil {
+		t.Error("regra MANTER não deve ajustar datas")
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jules-playground/lms-backend/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Checklists de Aprovação ---
+
+var errInvalidChecklist = errors.New("Checklist inválido: informe nome, itens e faixa de valor (mínimo menor ou igual ao máximo)")
+
+// checklistIncompleteError lista os itens obrigatórios não marcados na aprovação.
+type checklistIncompleteError struct {
+	Missing []string
+}
+
+func (e checklistIncompleteError) Error() string {
+	return "Checklist incompleto, faltam itens obrigatórios: " + strings.Join(e.Missing, ", ")
+}
+
+// ChecklistItem é um item a conferir na aprovação.
+type ChecklistItem struct {
+	ID        string `json:"id" bson:"id"`
+	Label     string `json:"label" bson:"label"`
+	Mandatory bool   `json:"mandatory" bson:"mandatory"`
+}
+
+// ChecklistTemplate é um modelo de checklist, escolhido pelo produto e pela
+// faixa de valor do contrato. Version sobe a cada alteração do modelo.
+type ChecklistTemplate struct {
+	ID        string          `json:"id" bson:"id"`
+	Name      string          `json:"name" bson:"name"`
+	Version   int             `json:"version" bson:"version"`
+	Product   string          `json:"product,omitempty" bson:"product,omitempty"`     // vazio vale para qualquer produto
+	MinAmount money.Cents     `json:"minAmount,omitempty" bson:"minAmount,omitempty"` // inclusive
+	MaxAmount money.Cents     `json:"maxAmount,omitempty" bson:"maxAmount,omitempty"` // inclusive; zero é sem teto
+	Items     []ChecklistItem `json:"items" bson:"items"`
+	UpdatedAt string          `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	UpdatedBy string          `json:"updatedBy,omitempty" bson:"updatedBy,omitempty"`
+}
+
+// ChecklistSnapshot é a cópia do modelo, na versão vigente, gravada no
+// contrato aprovado junto com os itens marcados.
+type ChecklistSnapshot struct {
+	TemplateID string          `json:"templateId" bson:"templateId"`
+	Name       string          `json:"name" bson:"name"`
+	Version    int             `json:"version" bson:"version"`
+	Items      []ChecklistItem `json:"items" bson:"items"`
+	Checked    []string        `json:"checked" bson:"checked"` // IDs dos itens marcados
+	ApprovedBy string          `json:"approvedBy" bson:"approvedBy"`
+	ApprovedAt string          `json:"approvedAt" bson:"approvedAt"`
+}
+
+func (t ChecklistTemplate) matches(l Loan) bool {
+	if t.Product != "" && !strings.EqualFold(t.Product, l.Product) {
+		return false
+	}
+	return l.Amount >= t.MinAmount && (t.MaxAmount == 0 || l.Amount <= t.MaxAmount)
+}
+
+// selectChecklist escolhe o modelo do contrato: modelos do produto têm
+// preferência sobre os genéricos e, entre eles, vale a faixa de valor mais
+// estreita. Devolve nil quando nenhum modelo se aplica.
+func selectChecklist(templates []ChecklistTemplate, l Loan) *ChecklistTemplate {
+	var best *ChecklistTemplate
+	for i := range templates {
+		t := &templates[i]
+		if !t.matches(l) {
+			continue
+		}
+		if best == nil || betterChecklist(t, best) {
+			best = t
+		}
+	}
+	return best
+}
+
+func betterChecklist(a, b *ChecklistTemplate) bool {
+	if (a.Product != "") != (b.Product != "") {
+		return a.Product != ""
+	}
+	width := func(t *ChecklistTemplate) money.Cents {
+		if t.MaxAmount == 0 {
+			return money.Cents(1<<62) - t.MinAmount
+		}
+		return t.MaxAmount - t.MinAmount
+	}
+	return width(a) < width(b)
+}
+
+// checkChecklist confere os itens marcados (por ID ou texto) contra o modelo e
+// devolve os IDs marcados e os obrigatórios que faltaram.
+func checkChecklist(t ChecklistTemplate, ticked []string) (checked []string, missing []string) {
+	seen := map[string]bool{}
+	for _, s := range ticked {
+		seen[strings.ToLower(strings.TrimSpace(s))] = true
+	}
+	checked = []string{}
+	for _, it := range t.Items {
+		if seen[strings.ToLower(it.ID)] || seen[strings.ToLower(it.Label)] {
+			checked = append(checked, it.ID)
+		} else if it.Mandatory {
+			missing = append(missing, it.Label)
+		}
+	}
+	return checked, missing
+}
+
+// applyChecklist confere os itens marcados contra o modelo e grava no contrato
+// a cópia da versão usada. Sem modelo aplicável o contrato fica sem cópia.
+func applyChecklist(t *ChecklistTemplate, l *Loan, ticked []string, user string) error {
+	l.Checklist = nil
+	if t == nil {
+		return nil
+	}
+	checked, missing := checkChecklist(*t, ticked)
+	if len(missing) > 0 {
+		return checklistIncompleteError{Missing: missing}
+	}
+	l.Checklist = &ChecklistSnapshot{
+		TemplateID: t.ID,
+		Name:       t.Name,
+		Version:    t.Version,
+		Items:      t.Items,
+		Checked:    checked,
+		ApprovedBy: user,
+		ApprovedAt: time.Now().Format(time.RFC3339),
+	}
+	l.ChecklistAtApproval = l.Checklist.checklistLabels()
+	return nil
+}
+
+// checklistLabels devolve os textos dos itens marcados, como gravado em
+// Loan.ChecklistAtApproval.
+func (s ChecklistSnapshot) checklistLabels() []string {
+	ids := map[string]bool{}
+	for _, id := range s.Checked {
+		ids[id] = true
+	}
+	var labels []string
+	for _, it := range s.Items {
+		if ids[it.ID] {
+			labels = append(labels, it.Label)
+		}
+	}
+	return labels
+}
+
+// prepareChecklists valida os modelos enviados, gera os IDs que faltam e sobe
+// a versão dos modelos alterados em relação aos gravados.
+func prepareChecklists(templates, current []ChecklistTemplate, user string) ([]ChecklistTemplate, error) {
+	byID := map[string]ChecklistTemplate{}
+	for _, t := range current {
+		byID[t.ID] = t
+	}
+	now := time.Now().Format(time.RFC3339)
+	for i := range templates {
+		t := &templates[i]
+		t.Name = strings.TrimSpace(t.Name)
+		t.Product = strings.TrimSpace(t.Product)
+		if t.Name == "" || len(t.Items) == 0 || t.MinAmount < 0 || t.MaxAmount < 0 || (t.MaxAmount > 0 && t.MinAmount > t.MaxAmount) {
+			return nil, errInvalidChecklist
+		}
+		used := map[string]bool{}
+		for j := range t.Items {
+			it := &t.Items[j]
+			it.Label = strings.TrimSpace(it.Label)
+			if it.Label == "" {
+				return nil, errInvalidChecklist
+			}
+			if it.ID == "" || used[it.ID] {
+				it.ID = fmt.Sprintf("item%d", j+1)
+				for used[it.ID] {
+					it.ID += "b"
+				}
+			}
+			used[it.ID] = true
+		}
+		if t.ID == "" {
+			t.ID = primitive.NewObjectID().Hex()
+		}
+		old, ok := byID[t.ID]
+		switch {
+		case !ok:
+			t.Version, t.UpdatedAt, t.UpdatedBy = 1, now, user
+		case old.Name != t.Name || old.Product != t.Product || old.MinAmount != t.MinAmount ||
+			old.MaxAmount != t.MaxAmount || !reflect.DeepEqual(old.Items, t.Items):
+			t.Version, t.UpdatedAt, t.UpdatedBy = old.Version+1, now, user
+		default:
+			t.Version, t.UpdatedAt, t.UpdatedBy = old.Version, old.UpdatedAt, old.UpdatedBy
+		}
+	}
+	if templates == nil {
+		templates = []ChecklistTemplate{}
+	}
+	return templates, nil
+}
+
+// checklistsHandler lista os modelos de checklist (?amount=&product= devolve o
+// modelo que se aplica ao contrato) e, para administradores, substitui a lista.
+func checklistsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		templates := loadSettings(ctx).System.ChecklistTemplates
+		if templates == nil {
+			templates = []ChecklistTemplate{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		q := r.URL.Query()
+		if q.Has("amount") || q.Has("product") {
+			var amount money.Cents
+			if a := q.Get("amount"); a != "" {
+				if err := json.Unmarshal([]byte(a), &amount); err != nil {
+					http.Error(w, "Valor inválido", http.StatusBadRequest)
+					return
+				}
+			}
+			json.NewEncoder(w).Encode(selectChecklist(templates, Loan{Amount: amount, Product: q.Get("product")}))
+			return
+		}
+		json.NewEncoder(w).Encode(templates)
+	case http.MethodPut:
+		user := currentUser(r)
+		if !isAdminUser(ctx, user) {
+			logAction("ACESSO NEGADO ADMIN", user)
+			http.Error(w, "Acesso restrito a Administradores.", http.StatusForbidden)
+			return
+		}
+		var templates []ChecklistTemplate
+		if err := json.NewDecoder(r.Body).Decode(&templates); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		templates, err := prepareChecklists(templates, loadSettings(ctx).System.ChecklistTemplates, user)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts := options.Update().SetUpsert(true)
+		if _, err := settingsCollection.UpdateOne(ctx, bson.M{}, bson.M{"$set": bson.M{"system.checklistTemplates": templates}}, opts); err != nil {
+			http.Error(w, "Erro ao salvar checklists", http.StatusInternalServerError)
+			return
+		}
+		var names []string
+		for _, t := range templates {
+			names = append(names, fmt.Sprintf("%s v%d", t.Name, t.Version))
+		}
+		logUserAction("CHECKLISTS DE APROVAÇÃO", user, fmt.Sprintf("%d modelo(s): %s", len(templates), strings.Join(names, ", ")))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(templates)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jules-playground/lms-backend/score"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// --- Score Interno de Crédito ---
+
+// ClientScore é a nota gravada no cadastro do cliente.
+type ClientScore struct {
+	score.Result `bson:",inline"`
+	CalculatedAt string `json:"calculatedAt" bson:"calculatedAt"`
+}
+
+// scoreInput resume o histórico dos contratos do CPF do cliente: parcelas
+// pagas (no prazo ou com atraso até o pagamento), parcelas vencidas em aberto
+// (atraso até a data de referência), acordos, baixas e o primeiro contrato.
+func scoreInput(ctx context.Context, c Client, ref time.Time) (score.Input, error) {
+	var in score.Input
+	names, err := sameCPFClientNames(ctx, c)
+	if err != nil {
+		return in, err
+	}
+	cursor, err := loanCollection.Find(ctx, bson.M{"client": bson.M{"$in": names}})
+	if err != nil {
+		return in, err
+	}
+	var loans []Loan
+	if err := cursor.All(ctx, &loans); err != nil {
+		return in, err
+	}
+
+	count := func(items []LoanInstallment) {
+		for _, it := range items {
+			switch {
+			case it.Status == InstallmentRenegotiated:
+			case it.Status == InstallmentPaid:
+				in.Installments++
+				paidAt, err := parseLoanDate(it.PaidAt)
+				if err != nil {
+					in.OnTime++
+					continue
+				}
+				if days := chargeableDaysLate(it.DueDate, paidAt); days > 0 {
+					in.DaysLate += days
+				} else {
+					in.OnTime++
+				}
+			case it.Status == InstallmentOverdue:
+				in.Installments++
+				in.DaysLate += chargeableDaysLate(it.DueDate, ref)
+			}
+		}
+	}
+	for _, l := range loans {
+		if start, err := parseLoanDate(l.StartDate); err == nil && (in.ClientSince.IsZero() || start.Before(in.ClientSince)) {
+			in.ClientSince = start
+		}
+		in.Agreements += len(l.Agreements)
+		if l.WriteOff != nil {
+			in.WriteOffs++
+		}
+		if ensureLoanSchedule(&l) != nil {
+			continue
+		}
+		applyPaymentsToSchedule(&l, ref)
+		count(l.Schedule)
+		for _, a := range l.Agreements {
+			count(a.Schedule)
+		}
+	}
+	return in, nil
+}
+
+// refreshClientScore recalcula a nota do cliente e grava no cadastro.
+func refreshClientScore(ctx context.Context, c Client) (ClientScore, error) {
+	ref := today()
+	in, err := scoreInput(ctx, c, ref)
+	if err != nil {
+		return ClientScore{}, err
+	}
+	s := ClientScore{Result: score.Calculate(in, time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, time.UTC)), CalculatedAt: time.Now().Format(time.RFC3339)}
+	_, err = clientCollection.UpdateOne(ctx, bson.M{"id": c.ID}, bson.M{"$set": bson.M{"score": s}})
+	return s, err
+}
+
+// StartClientScoreRoutine recalcula a nota de todos os clientes todo dia às
+// 01:00 (horário de São Paulo), depois da rotina de situação dos contratos.
+func StartClientScoreRoutine() {
+	go func() {
+		for {
+			now := time.Now().In(saoPaulo)
+			nextRun := time.Date(now.Year(), now.Month(), now.Day(), 1, 0, 0, 0, saoPaulo)
+			if now.After(nextRun) {
+				nextRun = nextRun.Add(24 * time.Hour)
+			}
+			time.Sleep(time.Until(nextRun))
+			performClientScoreUpdate()
+		}
+	}()
+}
+
+func performClientScoreUpdate() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	cursor, err := clientCollection.Find(ctx, bson.M{})
+	if err != nil {
+		log.Println("⚠️ Rotina de score:", err)
+		return
+	}
+	var clients []Client
+	if err := cursor.All(ctx, &clients); err != nil {
+		log.Println("⚠️ Rotina de score:", err)
+		return
+	}
+	failed := 0
+	for _, c := range clients {
+		if _, err := refreshClientScore(ctx, c); err != nil {
+			failed++
+		}
+	}
+	logSysAction("ROTINA DE SCORE", fmt.Sprintf("%d clientes avaliados, %d com erro.", len(clients), failed))
+}
+
+// clientScoreHandler atende GET /api/clients/{id}/score, recalculando a nota.
+func clientScoreHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var c Client
+	if err := clientCollection.FindOne(ctx, bson.M{"id": id}).Decode(&c); err == mongo.ErrNoDocuments {
+		http.Error(w, "Cliente não encontrado", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Erro ao buscar cliente", http.StatusInternalServerError)
+		return
+	}
+	s, err := refreshClientScore(ctx, c)
+	if err != nil {
+		http.Error(w, "Erro ao calcular score", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
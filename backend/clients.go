@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jules-playground/lms-backend/dedup"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Cliente Único por CPF: Duplicados e Mesclagem ---
+
+var (
+	errClientNotFound   = errors.New("Cliente não encontrado")
+	errMergeSameClient  = errors.New("Informe um cliente duplicado diferente do cliente mantido")
+	errMergeCPFMismatch = errors.New("Os cadastros têm CPFs diferentes e não podem ser mesclados")
+)
+
+// ensureClientIndexes cria o índice único do CPF canônico. Cadastros sem CPF
+// ficam fora do índice. Se a base já tiver CPFs repetidos a criação falha: o
+// servidor sobe assim mesmo e os repetidos aparecem em /api/clients/duplicates.
+func ensureClientIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := clientCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "cpfDigits", Value: 1}},
+		Options: options.Index().SetName("cpfDigits_unique").SetUnique(true).
+			SetPartialFilterExpression(bson.M{"cpfDigits": bson.M{"$gt": ""}}),
+	})
+	if err != nil {
+		log.Println("⚠️ Índice único de CPF não criado (há CPFs repetidos? veja /api/clients/duplicates):", err)
+	}
+}
+
+func findClientByID(ctx context.Context, id int64) (Client, error) {
+	var c Client
+	err := clientCollection.FindOne(ctx, bson.M{"id": id}).Decode(&c)
+	return c, err
+}
+
+// checkCPFAvailable confere se o CPF do cliente já pertence a outro cadastro.
+// O índice único garante a regra; a consulta só dá uma mensagem melhor.
+func checkCPFAvailable(ctx context.Context, c Client) (fieldErrors, error) {
+	if c.CPFDigits == "" {
+		return nil, nil
+	}
+	var other Client
+	err := clientCollection.FindOne(ctx, bson.M{"cpfDigits": c.CPFDigits, "id": bson.M{"$ne": c.ID}}).Decode(&other)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cpfTaken(other), nil
+}
+
+func cpfTaken(other Client) fieldErrors {
+	msg := "CPF já cadastrado"
+	if other.Name != "" {
+		msg = fmt.Sprintf("CPF já cadastrado para %s (cliente %d)", other.Name, other.ID)
+	}
+	return fieldErrors{"cpf": msg}
+}
+
+// saveClient grava o cliente (novo ou existente) respeitando o CPF único.
+// Devolve os erros por campo quando o CPF já pertence a outro cadastro.
+func saveClient(ctx context.Context, c Client, insert bool) (fieldErrors, error) {
+	fe, err := checkCPFAvailable(ctx, c)
+	if err != nil || len(fe) > 0 {
+		return fe, err
+	}
+	if insert {
+		_, err = clientCollection.InsertOne(ctx, c)
+	} else {
+		_, err = clientCollection.ReplaceOne(ctx, bson.M{"id": c.ID}, c)
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return cpfTaken(Client{}), nil
+	}
+	return nil, err
+}
+
+// ClientRef resume o cadastro na lista de duplicados, sem os documentos.
+type ClientRef struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	CPF   string `json:"cpf,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// DuplicateCandidate é um par de cadastros que parecem ser a mesma pessoa.
+type DuplicateCandidate struct {
+	Clients        [2]ClientRef `json:"clients"`
+	Reasons        []string     `json:"reasons"` // CPF, Telefone, Nome
+	NameSimilarity float64      `json:"nameSimilarity"`
+}
+
+// findDuplicates compara os cadastros dois a dois por CPF, telefone e nome
+// aproximado. Com clientID > 0 só devolve os pares que incluem esse cliente.
+func findDuplicates(clients []Client, clientID int64) []DuplicateCandidate {
+	res := []DuplicateCandidate{}
+	for i := range clients {
+		for j := i + 1; j < len(clients); j++ {
+			a, b := clients[i], clients[j]
+			if clientID > 0 && a.ID != clientID && b.ID != clientID {
+				continue
+			}
+			var reasons []string
+			if a.CPFDigits != "" && a.CPFDigits == b.CPFDigits {
+				reasons = append(reasons, "CPF")
+			}
+			if k := dedup.PhoneKey(a.Phone); k != "" && k == dedup.PhoneKey(b.Phone) {
+				reasons = append(reasons, "Telefone")
+			}
+			sim := dedup.NameSimilarity(a.Name, b.Name)
+			if sim >= dedup.NameThreshold {
+				reasons = append(reasons, "Nome")
+			}
+			if len(reasons) == 0 {
+				continue
+			}
+			res = append(res, DuplicateCandidate{
+				Clients:        [2]ClientRef{clientRef(a), clientRef(b)},
+				Reasons:        reasons,
+				NameSimilarity: float64(int(sim*100)) / 100,
+			})
+		}
+	}
+	return res
+}
+
+func clientRef(c Client) ClientRef {
+	return ClientRef{ID: c.ID, Name: c.Name, CPF: c.CPF, Phone: c.Phone}
+}
+
+// clientDuplicatesHandler atende GET /api/clients/duplicates[?clientId=].
+func clientDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var clientID int64
+	fmt.Sscan(r.URL.Query().Get("clientId"), &clientID)
+	opts := options.Find().SetProjection(bson.M{"id": 1, "name": 1, "cpf": 1, "cpfDigits": 1, "phone": 1})
+	cursor, err := clientCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		http.Error(w, "Erro ao buscar clientes", http.StatusInternalServerError)
+		return
+	}
+	var clients []Client
+	if err := cursor.All(ctx, &clients); err != nil {
+		http.Error(w, "Erro ao buscar clientes", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(findDuplicates(clients, clientID))
+}
+
+type mergeRequest struct {
+	DuplicateID int64 `json:"duplicateId"`
+}
+
+// MergeResult resume a mesclagem.
+type MergeResult struct {
+	Client       Client `json:"client"`
+	Loans        int64  `json:"loans"`        // contratos transferidos
+	Applications int64  `json:"applications"` // propostas transferidas
+	Documents    int    `json:"documents"`    // documentos copiados
+}
+
+// mergeClients transfere para o cliente mantido os contratos (com o histórico
+// de pagamentos), as propostas e os documentos do duplicado, completa os dados
+// cadastrais em branco e remove o duplicado. Os passos podem ser repetidos: se
+// algo falhar no meio, rodar de novo termina a mesclagem sem duplicar nada.
+func mergeClients(ctx context.Context, survivorID, duplicateID int64, user string) (MergeResult, error) {
+	if duplicateID == 0 || duplicateID == survivorID {
+		return MergeResult{}, errMergeSameClient
+	}
+	var keep, dup Client
+	if err := clientCollection.FindOne(ctx, bson.M{"id": survivorID}).Decode(&keep); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return MergeResult{}, errClientNotFound
+		}
+		return MergeResult{}, err
+	}
+	if err := clientCollection.FindOne(ctx, bson.M{"id": duplicateID}).Decode(&dup); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return MergeResult{}, errClientNotFound
+		}
+		return MergeResult{}, err
+	}
+	if keep.CPFDigits != "" && dup.CPFDigits != "" && keep.CPFDigits != dup.CPFDigits {
+		return MergeResult{}, errMergeCPFMismatch
+	}
+
+	// O CPF do duplicado só pode passar para o mantido depois que o duplicado
+	// sair da base, por causa do índice único.
+	adoptCPF := keep.CPFDigits == "" && dup.CPFDigits != ""
+	res := MergeResult{Documents: mergeClientData(&keep, dup)}
+	if _, err := clientCollection.ReplaceOne(ctx, bson.M{"id": keep.ID}, keep); err != nil {
+		return res, err
+	}
+
+	if dup.Name != keep.Name {
+		ur, err := loanCollection.UpdateMany(ctx, bson.M{"client": dup.Name},
+			bson.M{"$set": bson.M{"client": keep.Name}, "$inc": bson.M{"version": 1}})
+		if err != nil {
+			return res, err
+		}
+		res.Loans = ur.ModifiedCount
+		ur, err = applicationCollection.UpdateMany(ctx, bson.M{"loan.client": dup.Name},
+			bson.M{"$set": bson.M{"loan.client": keep.Name}})
+		if err != nil {
+			return res, err
+		}
+		res.Applications = ur.ModifiedCount
+	}
+
+	if _, err := clientCollection.DeleteOne(ctx, bson.M{"id": dup.ID}); err != nil {
+		return res, err
+	}
+	if adoptCPF {
+		keep.CPF, keep.CPFDigits = dup.CPF, dup.CPFDigits
+		if _, err := clientCollection.UpdateOne(ctx, bson.M{"id": keep.ID},
+			bson.M{"$set": bson.M{"cpf": keep.CPF, "cpfDigits": keep.CPFDigits}}); err != nil {
+			return res, err
+		}
+	}
+	if s, err := refreshClientScore(ctx, keep); err == nil {
+		keep.Score = &s
+	}
+	res.Client = keep
+
+	logUserAction("MESCLAGEM DE CLIENTES", user, fmt.Sprintf(
+		"Cliente %d (%s) mesclado em %d (%s): %d contrato(s), %d proposta(s), %d documento(s)",
+		dup.ID, dup.Name, keep.ID, keep.Name, res.Loans, res.Applications, res.Documents))
+	return res, nil
+}
+
+// mergeClientData copia os documentos que o mantido ainda não tem e preenche
+// os campos em branco com os do duplicado. Devolve quantos documentos copiou.
+func mergeClientData(keep *Client, dup Client) int {
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fill(&keep.RG, dup.RG)
+	fill(&keep.Email, dup.Email)
+	fill(&keep.Phone, dup.Phone)
+	fill(&keep.Address, dup.Address)
+	fill(&keep.Number, dup.Number)
+	fill(&keep.Neighborhood, dup.Neighborhood)
+	fill(&keep.City, dup.City)
+	fill(&keep.State, dup.State)
+	fill(&keep.CEP, dup.CEP)
+	if dup.Observations != "" && dup.Observations != keep.Observations {
+		if keep.Observations != "" {
+			keep.Observations += "\n"
+		}
+		keep.Observations += dup.Observations
+	}
+	if keep.CreditLimit == 0 {
+		keep.CreditLimit = dup.CreditLimit
+	}
+
+	has := map[string]bool{}
+	for _, d := range keep.Documents {
+		has[d.Name+"|"+d.Data] = true
+	}
+	n := 0
+	for _, d := range dup.Documents {
+		if !has[d.Name+"|"+d.Data] {
+			keep.Documents = append(keep.Documents, d)
+			has[d.Name+"|"+d.Data] = true
+			n++
+		}
+	}
+	return n
+}
+
+// clientMergeHandler atende POST /api/clients/{id}/merge (só administradores).
+func clientMergeHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	user := currentUser(r)
+	if !isAdminUser(ctx, user) {
+		logAction("ACESSO NEGADO ADMIN", user)
+		http.Error(w, "Acesso restrito a Administradores.", http.StatusForbidden)
+		return
+	}
+	var req mergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	res, err := mergeClients(ctx, id, req.DuplicateID, user)
+	switch err {
+	case nil:
+	case errMergeSameClient:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errClientNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errMergeCPFMismatch:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
+		http.Error(w, "Erro ao mesclar clientes", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,57 @@
+// Package commission calcula a comissão de afiliados sobre os contratos
+// indicados: na originação (sobre o valor emprestado) ou sobre os juros
+// efetivamente recebidos.
+package commission
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jules-playground/lms-backend/money"
+)
+
+// Bases de cálculo da comissão.
+const (
+	BaseOrigination = "ORIGINACAO" // % do valor do contrato, devida na contratação
+	BaseInterest    = "JUROS"      // % dos juros recebidos, acompanha os pagamentos
+)
+
+var ErrInvalidBase = errors.New("base de comissão deve ser ORIGINACAO ou JUROS")
+
+// NormalizeBase aceita a base sem caixa ou acento; vazia é ORIGINACAO.
+func NormalizeBase(s string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "", BaseOrigination, "ORIGINAÇÃO":
+		return BaseOrigination, nil
+	case BaseInterest:
+		return BaseInterest, nil
+	}
+	return "", ErrInvalidBase
+}
+
+// Terms são as condições de comissão congeladas no contrato quando ele é
+// vinculado ao afiliado; mudanças posteriores no afiliado não o afetam.
+type Terms struct {
+	Base     string      `json:"base" bson:"base"`
+	Rate     float64     `json:"rate" bson:"rate"`                             // % sobre a base
+	Fixed    money.Cents `json:"fixed" bson:"fixed"`                           // valor fixo por contrato, devido na contratação
+	Rollover money.Cents `json:"rollover,omitempty" bson:"rollover,omitempty"` // saldo rolado de outro contrato, fora da base de originação
+}
+
+// Amount devolve a comissão devida até agora: o valor fixo mais Rate% do valor
+// do contrato (ORIGINACAO) ou dos juros já recebidos (JUROS). Na originação o
+// saldo rolado não entra: ele já foi comissionado no contrato de origem.
+func (t Terms) Amount(principal, interestReceived money.Cents) money.Cents {
+	base := principal - t.Rollover
+	if t.Base == BaseInterest {
+		base = interestReceived
+	}
+	return t.Fixed + max(0, base).Percent(t.Rate)
+}
+
+// ForRollover devolve as condições do contrato que refinancia outro: o saldo
+// rolado sai da base de originação e o valor fixo não é pago de novo.
+func (t Terms) ForRollover(rolled money.Cents) Terms {
+	t.Fixed, t.Rollover = 0, rolled
+	return t
+}
@@ -0,0 +1,49 @@
+package commission
+
+import (
+	"testing"
+
+	"github.com/jules-playground/lms-backend/money"
+)
+
+func TestNormalizeBase(t *testing.T) {
+	tests := []struct {
+		in, want string
+		err      error
+	}{
+		{"", BaseOrigination, nil},
+		{"originação", BaseOrigination, nil},
+		{"juros", BaseInterest, nil},
+		{"LUCRO", "", ErrInvalidBase},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeBase(tt.in)
+		if got != tt.want || err != tt.err {
+			t.Errorf("NormalizeBase(%q) = %q, %v; want %q, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		name      string
+		terms     Terms
+		principal money.Cents
+		interest  money.Cents
+		want      money.Cents
+	}{
+		{"originação", Terms{Base: BaseOrigination, Rate: 2}, 1000000, 50000, 20000},
+		{"originação com fixo", Terms{Base: BaseOrigination, Rate: 1.5, Fixed: 5000}, 1000000, 0, 20000},
+		{"juros recebidos", Terms{Base: BaseInterest, Rate: 10}, 1000000, 123456, 12346},
+		{"juros ainda não recebidos", Terms{Base: BaseInterest, Rate: 10, Fixed: 2500}, 1000000, 0, 2500},
+		{"sem taxa", Terms{Base: BaseOrigination}, 1000000, 0, 0},
+		{"refinanciamento só com saldo rolado", Terms{Base: BaseOrigination, Rate: 2, Fixed: 5000}.ForRollover(800000), 800000, 0, 0},
+		{"refinanciamento com dinheiro novo", Terms{Base: BaseOrigination, Rate: 2, Fixed: 5000}.ForRollover(800000), 1000000, 0, 4000},
+		{"refinanciamento na base juros", Terms{Base: BaseInterest, Rate: 10, Fixed: 5000}.ForRollover(800000), 1000000, 30000, 3000},
+	}
+	for _, tt := range tests {
+		if got := tt.terms.Amount(tt.principal, tt.interest); got != tt.want {
+			t.Errorf("%s: Amount = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jules-playground/lms-backend/finance"
+	"github.com/jules-playground/lms-backend/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Correção Monetária por Índices ---
+
+// Índices aceitos nas tabelas e nos contratos/acordos.
+const (
+	IndexIPCA = "IPCA"
+	IndexIGPM = "IGPM"
+	IndexCDI  = "CDI"
+)
+
+var (
+	errInvalidIndex    = errors.New("Índice inválido: use IPCA, IGPM ou CDI")
+	errInvalidIndexRow = errors.New("Linha inválida: informe mês (AAAA-MM ou MM/AAAA) e variação em %")
+)
+
+// indexRateCollection guarda uma linha por índice e mês ({index, month, rate}).
+var indexRateCollection *mongo.Collection
+
+// IndexRate é a variação mensal de um índice, em percentual.
+type IndexRate struct {
+	Index string  `json:"index" bson:"index"`
+	Month string  `json:"month" bson:"month"` // AAAA-MM
+	Rate  float64 `json:"rate" bson:"rate"`
+}
+
+var indexTables atomic.Pointer[map[string]finance.IndexTable]
+
+func normalizeIndex(name string) (string, bool) {
+	name = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(name), "-", ""))
+	switch name {
+	case IndexIPCA, IndexIGPM, IndexCDI:
+		return name, true
+	}
+	return "", false
+}
+
+// indexTable devolve a tabela carregada do índice (vazia se não houver).
+func indexTable(name string) finance.IndexTable {
+	if t := indexTables.Load(); t != nil {
+		return (*t)[name]
+	}
+	return nil
+}
+
+// reloadIndexTables relê as tabelas; chamado na subida e após cada edição.
+func reloadIndexTables(ctx context.Context) error {
+	cursor, err := indexRateCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	var rows []IndexRate
+	if err := cursor.All(ctx, &rows); err != nil {
+		return err
+	}
+	tables := map[string]finance.IndexTable{}
+	for _, r := range rows {
+		if tables[r.Index] == nil {
+			tables[r.Index] = finance.IndexTable{}
+		}
+		tables[r.Index][r.Month] = r.Rate
+	}
+	indexTables.Store(&tables)
+	return nil
+}
+
+// --- Cálculo por Parcela ---
+
+func agreementByID(l Loan, id string) *Agreement {
+	for i := range l.Agreements {
+		if l.Agreements[i].ID == id {
+			return &l.Agreements[i]
+		}
+	}
+	return nil
+}
+
+// installmentCorrectionBase devolve índice, base e data inicial da correção da
+// parcela. Parcelas do contrato corrigem o saldo em aberto desde o vencimento,
+// só depois de vencidas; parcelas de acordo corrigem desde a criação do acordo.
+func installmentCorrectionBase(l Loan, in openInstallment, ref time.Time) (string, money.Cents, string) {
+	index, from := l.CorrectionIndex, in.DueDate
+	if in.Agreement != "" {
+		a := agreementByID(l, in.Agreement)
+		if a == nil {
+			return "", 0, ""
+		}
+		index, from = a.CorrectionIndex, a.CreatedAt
+	} else if !isOverdue(in.DueDate, ref) {
+		return "", 0, ""
+	}
+	base := in.ExpectedCapital + in.ExpectedInterest - in.PaidCapital - in.PaidInterest - in.InterestDiscount
+	if index == "" || base <= 0 {
+		return "", 0, ""
+	}
+	return index, base, from
+}
+
+// installmentCorrection devolve a correção monetária ainda devida da parcela na
+// data de referência, já descontada a correção paga.
+func installmentCorrection(l Loan, in openInstallment, ref time.Time) money.Cents {
+	c, _ := correctionDetail(l, in, ref)
+	return c.Correction
+}
+
+// IndexCorrection resume a correção de um índice na quitação.
+type IndexCorrection struct {
+	Index         string      `json:"index"`
+	Base          money.Cents `json:"base"`       // saldo em aberto corrigido
+	Correction    money.Cents `json:"correction"` // acréscimo ainda devido
+	Corrected     money.Cents `json:"corrected"`  // base + correção
+	MissingMonths []string    `json:"missingMonths,omitempty"`
+}
+
+func correctionDetail(l Loan, in openInstallment, ref time.Time) (IndexCorrection, bool) {
+	index, base, from := installmentCorrectionBase(l, in, ref)
+	if index == "" {
+		return IndexCorrection{}, false
+	}
+	start, err := parseLoanDate(from)
+	if err != nil {
+		return IndexCorrection{}, false
+	}
+	value, missing := finance.Correct(indexTable(index), base.Float(), start, ref)
+	c := IndexCorrection{
+		Index:         index,
+		Base:          base,
+		Correction:    max(0, money.FromFloat(value)-in.PaidCorrection),
+		MissingMonths: missing,
+	}
+	c.Corrected = c.Base + c.Correction
+	return c, true
+}
+
+// addCorrection acumula a correção de uma parcela no resumo por índice.
+func addCorrection(list []IndexCorrection, c IndexCorrection) []IndexCorrection {
+	for i := range list {
+		if list[i].Index != c.Index {
+			continue
+		}
+		list[i].Base += c.Base
+		list[i].Correction += c.Correction
+		list[i].Corrected += c.Corrected
+		for _, m := range c.MissingMonths {
+			if !containsString(list[i].MissingMonths, m) {
+				list[i].MissingMonths = append(list[i].MissingMonths, m)
+			}
+		}
+		return list
+	}
+	return append(list, c)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Tabelas de Índices (API) ---
+
+// indexesHandler lista as tabelas (?index= filtra um índice).
+func indexesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	filter := bson.M{}
+	if q := r.URL.Query().Get("index"); q != "" {
+		index, ok := normalizeIndex(q)
+		if !ok {
+			http.Error(w, errInvalidIndex.Error(), http.StatusBadRequest)
+			return
+		}
+		filter["index"] = index
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "index", Value: 1}, {Key: "month", Value: 1}})
+	cursor, err := indexRateCollection.Find(ctx, filter, opts)
+	if err != nil {
+		http.Error(w, "Erro ao buscar índices", http.StatusInternalServerError)
+		return
+	}
+	var rows []IndexRate
+	cursor.All(ctx, &rows)
+	if rows == nil {
+		rows = []IndexRate{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rows)
+}
+
+// indexTableHandler (admin) edita a tabela de um índice:
+// PUT /api/indexes/{índice} substitui a tabela pelo JSON [{month, rate}];
+// POST /api/indexes/{índice}/import grava as linhas de um CSV (mês;variação);
+// DELETE /api/indexes/{índice}/{AAAA-MM} remove um mês.
+func indexTableHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/indexes/"), "/"), "/")
+	index, ok := normalizeIndex(parts[0])
+	if !ok {
+		http.Error(w, errInvalidIndex.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	user := currentUser(r)
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		var rows []IndexRate
+		if err := json.NewDecoder(r.Body).Decode(&rows); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		for i := range rows {
+			month, ok := normalizeIndexMonth(rows[i].Month)
+			if !ok {
+				http.Error(w, fmt.Sprintf("Item %d: %s", i+1, errInvalidIndexRow), http.StatusBadRequest)
+				return
+			}
+			rows[i].Index, rows[i].Month = index, month
+		}
+		if _, err := indexRateCollection.DeleteMany(ctx, bson.M{"index": index}); err != nil {
+			http.Error(w, "Erro ao salvar índice", http.StatusInternalServerError)
+			return
+		}
+		if err := upsertIndexRates(ctx, rows); err != nil {
+			http.Error(w, "Erro ao salvar índice", http.StatusInternalServerError)
+			return
+		}
+		reloadIndexTables(ctx)
+		logUserAction("TABELA DE ÍNDICE", user, fmt.Sprintf("%s: tabela substituída (%d meses)", index, len(rows)))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rows)
+	case len(parts) == 2 && parts[1] == "import" && r.Method == http.MethodPost:
+		rows, err := parseIndexCSV(r.Body, index)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := upsertIndexRates(ctx, rows); err != nil {
+			http.Error(w, "Erro ao importar índice", http.StatusInternalServerError)
+			return
+		}
+		reloadIndexTables(ctx)
+		logUserAction("IMPORTAÇÃO DE ÍNDICE", user, fmt.Sprintf("%s: %d meses importados via CSV", index, len(rows)))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"index": index, "imported": len(rows)})
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		month, ok := normalizeIndexMonth(parts[1])
+		if !ok {
+			http.Error(w, errInvalidIndexRow.Error(), http.StatusBadRequest)
+			return
+		}
+		indexRateCollection.DeleteOne(ctx, bson.M{"index": index, "month": month})
+		reloadIndexTables(ctx)
+		logUserAction("TABELA DE ÍNDICE", user, fmt.Sprintf("%s: removido o mês %s", index, month))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func upsertIndexRates(ctx context.Context, rows []IndexRate) error {
+	for _, row := range rows {
+		_, err := indexRateCollection.ReplaceOne(ctx, bson.M{"index": row.Index, "month": row.Month}, row, options.Replace().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeIndexMonth aceita "2026-01", "01/2026" ou uma data "2026-01-15".
+func normalizeIndexMonth(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01", "01/2006", "2006-01-02", "02/01/2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("2006-01"), true
+		}
+	}
+	return "", false
+}
+
+// parseIndexCSV lê linhas "mês;variação" (ou separadas por vírgula), com
+// variação em % e vírgula ou ponto decimal, como exportado pelo Bacen/IBGE. Uma
+// linha de cabeçalho é ignorada.
+func parseIndexCSV(body io.Reader, index string) ([]IndexRate, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.Comma = ','
+	if strings.Contains(string(data), ";") {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV inválido: %v", err)
+	}
+	var rows []IndexRate
+	for i, rec := range records {
+		if len(rec) == 0 || (len(rec) == 1 && strings.TrimSpace(rec[0]) == "") {
+			continue
+		}
+		month, okMonth := "", false
+		var rate float64
+		var errRate error = errInvalidIndexRow
+		if len(rec) >= 2 {
+			month, okMonth = normalizeIndexMonth(rec[0])
+			rate, errRate = strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(rec[1]), ",", "."), 64)
+		}
+		if !okMonth || errRate != nil {
+			if i == 0 {
+				continue // cabeçalho
+			}
+			return nil, fmt.Errorf("Linha %d: %s", i+1, errInvalidIndexRow)
+		}
+		rows = append(rows, IndexRate{Index: index, Month: month, Rate: rate})
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("CSV sem linhas de índice")
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Month < rows[j].Month })
+	return rows, nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jules-playground/lms-backend/document"
+	"github.com/jules-playground/lms-backend/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// --- Limite de Crédito e Exposição por Cliente ---
+
+// Comportamento do loansHandler quando o contrato passa do limite do cliente
+// (SystemSettings.CreditLimitMode).
+const (
+	CreditLimitBlock = "BLOQUEAR"  // recusa, salvo justificativa de ADMIN
+	CreditLimitFlag  = "SINALIZAR" // grava o contrato marcado e registra na auditoria
+)
+
+// creditLimitError informa limite, exposição e valor do contrato recusado.
+type creditLimitError struct {
+	Limit    money.Cents
+	Exposure money.Cents
+	Amount   money.Cents
+}
+
+func (e creditLimitError) Error() string {
+	return fmt.Sprintf("Limite de crédito excedido: limite R$ %s, exposição atual R$ %s, contrato R$ %s. Só um Administrador pode liberar, com justificativa",
+		e.Limit, e.Exposure, e.Amount)
+}
+
+// CreditLimitOverride registra a liberação acima do limite. O front envia só a
+// justificativa; o restante é preenchido pelo servidor.
+type CreditLimitOverride struct {
+	Justification string      `json:"justification" bson:"justification"`
+	User          string      `json:"user,omitempty" bson:"user,omitempty"`
+	Date          string      `json:"date,omitempty" bson:"date,omitempty"`
+	Limit         money.Cents `json:"limit,omitempty" bson:"limit,omitempty"`
+	Exposure      money.Cents `json:"exposure,omitempty" bson:"exposure,omitempty"`
+}
+
+// ExposureLoan é o capital em aberto de um contrato do cliente.
+type ExposureLoan struct {
+	LoanID      string      `json:"loanId"`
+	Client      string      `json:"client"`
+	Status      string      `json:"status"`
+	OpenCapital money.Cents `json:"openCapital"`
+}
+
+// ClientExposure é a posição de crédito do cliente.
+type ClientExposure struct {
+	ClientID    int64          `json:"clientId"`
+	Name        string         `json:"name"`
+	CPF         string         `json:"cpf"`
+	CreditLimit money.Cents    `json:"creditLimit"` // zero é sem limite
+	Exposure    money.Cents    `json:"exposure"`
+	Available   money.Cents    `json:"available,omitempty"`
+	Loans       []ExposureLoan `json:"loans"`
+}
+
+func findClientByName(ctx context.Context, name string) (Client, error) {
+	var c Client
+	err := clientCollection.FindOne(ctx, bson.M{"name": name}).Decode(&c)
+	return c, err
+}
+
+// sameCPFClientNames devolve os nomes dos cadastros com o mesmo CPF, já que os
+// contratos guardam o nome do cliente.
+func sameCPFClientNames(ctx context.Context, c Client) ([]string, error) {
+	cpf := document.Digits(c.CPF)
+	if cpf == "" {
+		return []string{c.Name}, nil
+	}
+	cursor, err := clientCollection.Find(ctx, bson.M{"cpfDigits": cpf, "name": bson.M{"$ne": c.Name}})
+	if err != nil {
+		return nil, err
+	}
+	var clients []Client
+	if err := cursor.All(ctx, &clients); err != nil {
+		return nil, err
+	}
+	names := []string{c.Name}
+	for _, other := range clients {
+		names = append(names, other.Name)
+	}
+	return names, nil
+}
+
+// openCapital soma o capital ainda não pago das parcelas em aberto do contrato
+// (incluindo as de acordos ativos).
+func openCapital(l Loan) money.Cents {
+	if ensureLoanSchedule(&l) != nil {
+		return 0
+	}
+	applyPaymentsToSchedule(&l, today())
+	var total money.Cents
+	for _, in := range openInstallments(l) {
+		total += max(0, in.ExpectedCapital-in.PaidCapital)
+	}
+	return total
+}
+
+// clientExposure soma o capital em aberto de todos os contratos ativos do CPF
+// do cliente.
+func clientExposure(ctx context.Context, c Client) (ClientExposure, error) {
+	e := ClientExposure{ClientID: c.ID, Name: c.Name, CPF: c.CPF, CreditLimit: c.CreditLimit, Loans: []ExposureLoan{}}
+	names, err := sameCPFClientNames(ctx, c)
+	if err != nil {
+		return e, err
+	}
+	cursor, err := loanCollection.Find(ctx, bson.M{"client": bson.M{"$in": names}, "status": bson.M{"$nin": closedLoanStatuses}})
+	if err != nil {
+		return e, err
+	}
+	var loans []Loan
+	if err := cursor.All(ctx, &loans); err != nil {
+		return e, err
+	}
+	for _, l := range loans {
+		open := openCapital(l)
+		if open <= 0 {
+			continue
+		}
+		e.Exposure += open
+		e.Loans = append(e.Loans, ExposureLoan{LoanID: l.ID, Client: l.Client, Status: l.Status, OpenCapital: open})
+	}
+	if e.CreditLimit > 0 {
+		e.Available = max(0, e.CreditLimit-e.Exposure)
+	}
+	return e, nil
+}
+
+// creditLimitMode devolve o modo efetivo; ausente vale como BLOQUEAR.
+func (s SystemSettings) creditLimitMode() string {
+	if s.CreditLimitMode == CreditLimitFlag {
+		return CreditLimitFlag
+	}
+	return CreditLimitBlock
+}
+
+// authorizeCreditLimitChange deixa só ADMIN mudar o limite do cliente, já que é
+// ele que segura contratos acima da exposição. Responde 403 quando recusa.
+func authorizeCreditLimitChange(ctx context.Context, w http.ResponseWriter, user string, c Client, previous money.Cents) bool {
+	if c.CreditLimit == previous {
+		return true
+	}
+	if !isAdminUser(ctx, user) {
+		logAction("ACESSO NEGADO ADMIN", user)
+		http.Error(w, "Acesso restrito a Administradores.", http.StatusForbidden)
+		return false
+	}
+	logUserAction("LIMITE DE CRÉDITO ALTERADO", user, fmt.Sprintf("Cliente %s: de R$ %s para R$ %s", c.Name, previous, c.CreditLimit))
+	return true
+}
+
+// checkCreditLimit confere o contrato novo (ou editado) contra o limite do cliente. Acima do
+// limite, com justificativa de ADMIN o contrato é liberado e a liberação fica
+// gravada nele; sem ela, o modo BLOQUEAR recusa e o SINALIZAR grava o contrato
+// marcado. Clientes sem cadastro ou sem limite não são conferidos.
+func checkCreditLimit(ctx context.Context, l *Loan, user string) error {
+	c, err := findClientByName(ctx, l.Client)
+	if err == mongo.ErrNoDocuments || (err == nil && c.CreditLimit <= 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	e, err := clientExposure(ctx, c)
+	if err != nil {
+		return err
+	}
+	// Na edição o próprio contrato já está na exposição, com o valor anterior; no
+	// refinanciamento, o contrato que ele quita.
+	for _, el := range e.Loans {
+		if el.LoanID == l.ID || (l.RefinancedFrom != "" && el.LoanID == l.RefinancedFrom) {
+			e.Exposure -= el.OpenCapital
+		}
+	}
+	if e.Exposure+l.Amount <= c.CreditLimit {
+		l.CreditLimitOverride, l.CreditLimitExceeded = nil, false
+		return nil
+	}
+
+	if o := l.CreditLimitOverride; o != nil && strings.TrimSpace(o.Justification) != "" && isAdminUser(ctx, user) {
+		o.Justification = strings.TrimSpace(o.Justification)
+		o.User, o.Date = user, time.Now().Format(time.RFC3339)
+		o.Limit, o.Exposure = c.CreditLimit, e.Exposure
+		l.CreditLimitExceeded = true
+		logUserAction("LIMITE DE CRÉDITO LIBERADO", user, fmt.Sprintf("Contrato %s (%s): R$ %s com exposição de R$ %s e limite de R$ %s. Justificativa: %s",
+			l.ID, l.Client, l.Amount, e.Exposure, c.CreditLimit, o.Justification))
+		return nil
+	}
+	l.CreditLimitOverride = nil
+	if loadSettings(ctx).System.creditLimitMode() == CreditLimitFlag {
+		l.CreditLimitExceeded = true
+		logUserAction("LIMITE DE CRÉDITO EXCEDIDO", user, fmt.Sprintf("Contrato %s (%s) gravado acima do limite: R$ %s com exposição de R$ %s e limite de R$ %s",
+			l.ID, l.Client, l.Amount, e.Exposure, c.CreditLimit))
+		return nil
+	}
+	return creditLimitError{Limit: c.CreditLimit, Exposure: e.Exposure, Amount: l.Amount}
+}
+
+// clientExposureHandler atende GET /api/clients/{id}/exposure.
+func clientExposureHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var c Client
+	if err := clientCollection.FindOne(ctx, bson.M{"id": id}).Decode(&c); err == mongo.ErrNoDocuments {
+		http.Error(w, "Cliente não encontrado", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Erro ao buscar cliente", http.StatusInternalServerError)
+		return
+	}
+	e, err := clientExposure(ctx, c)
+	if err != nil {
+		http.Error(w, "Erro ao calcular exposição", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
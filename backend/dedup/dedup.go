@@ -0,0 +1,109 @@
+// Package dedup compara cadastros de clientes para achar possíveis duplicados:
+// nome aproximado (sem acentos, caixa e preposições) e telefone.
+package dedup
+
+import (
+	"sort"
+	"strings"
+)
+
+// NameThreshold é a similaridade mínima para dois nomes serem tratados como
+// o mesmo cliente.
+const NameThreshold = 0.85
+
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// particles são ignoradas na comparação: "Maria da Silva" = "Maria Silva".
+var particles = map[string]bool{"de": true, "da": true, "do": true, "das": true, "dos": true, "e": true}
+
+// NormalizeName deixa o nome em minúsculas, sem acentos, pontuação e
+// preposições, com um espaço entre as palavras.
+func NormalizeName(s string) string {
+	s = accents.Replace(strings.ToLower(s))
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	out := words[:0]
+	for _, w := range words {
+		if !particles[w] {
+			out = append(out, w)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// NameSimilarity devolve de 0 a 1 o quanto dois nomes se parecem, pela
+// distância de edição dos nomes normalizados. Também compara as palavras em
+// ordem alfabética, para "Silva Maria" casar com "Maria Silva".
+func NameSimilarity(a, b string) float64 {
+	na, nb := NormalizeName(a), NormalizeName(b)
+	if na == "" || nb == "" {
+		return 0
+	}
+	best := ratio(na, nb)
+	if s := ratio(sortWords(na), sortWords(nb)); s > best {
+		best = s
+	}
+	return best
+}
+
+// PhoneKey reduz o telefone aos últimos 8 dígitos, para casar números com e
+// sem DDI, DDD ou o nono dígito. Devolve "" se o número for curto demais.
+func PhoneKey(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	d := b.String()
+	if len(d) < 8 {
+		return ""
+	}
+	return d[len(d)-8:]
+}
+
+func sortWords(s string) string {
+	w := strings.Fields(s)
+	sort.Strings(w)
+	return strings.Join(w, " ")
+}
+
+func ratio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	max := len(ra)
+	if len(rb) > max {
+		max = len(rb)
+	}
+	if max == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(max)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
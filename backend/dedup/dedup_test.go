@@ -0,0 +1,50 @@
+package dedup
+
+import "testing"
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"José da Silva", "jose silva"},
+		{"  MARIA   DE  Souza ", "maria souza"},
+		{"João-Pedro dos Santos Jr.", "joao pedro santos jr"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeName(tt.in); got != tt.want {
+			t.Errorf("NormalizeName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		match bool
+	}{
+		{"José da Silva", "Jose Silva", true},
+		{"Maria Souza", "Souza Maria", true},
+		{"Antonio Pereira", "Antônio Perreira", true},
+		{"Maria Souza", "Mario Santos", false},
+		{"Ana Lima", "", false},
+	}
+	for _, tt := range tests {
+		got := NameSimilarity(tt.a, tt.b)
+		if (got >= NameThreshold) != tt.match {
+			t.Errorf("NameSimilarity(%q, %q) = %.2f, want match=%v", tt.a, tt.b, got, tt.match)
+		}
+	}
+}
+
+func TestPhoneKey(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"+55 (11) 98765-4321", "87654321"},
+		{"8765-4321", "87654321"},
+		{"11 9 8765 4321", "87654321"},
+		{"1234", ""},
+	}
+	for _, tt := range tests {
+		if got := PhoneKey(tt.in); got != tt.want {
+			t.Errorf("PhoneKey(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Package document valida e normaliza CPF e CNPJ: dígitos verificadores, forma
+// canônica (só dígitos, usada em buscas e índices) e forma de exibição.
+package document
+
+import (
+	"errors"
+	"strings"
+)
+
+// Tipos de documento.
+const (
+	KindCPF  = "CPF"
+	KindCNPJ = "CNPJ"
+)
+
+var (
+	ErrLength      = errors.New("documento deve ter 11 dígitos (CPF) ou 14 dígitos (CNPJ)")
+	ErrCPFLength   = errors.New("CPF deve ter 11 dígitos")
+	ErrCNPJLength  = errors.New("CNPJ deve ter 14 dígitos")
+	ErrCPFDigits   = errors.New("CPF inválido: dígitos verificadores não conferem")
+	ErrCNPJDigits  = errors.New("CNPJ inválido: dígitos verificadores não conferem")
+	ErrInvalidChar = errors.New("documento contém caracteres inválidos")
+)
+
+// Document é um CPF ou CNPJ validado.
+type Document struct {
+	Kind    string
+	Digits  string // forma canônica: 12345678909
+	Display string // forma de exibição: 123.456.789-09
+}
+
+// Digits remove pontuação, espaços e qualquer outro caractere não numérico.
+func Digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Parse aceita CPF ou CNPJ, com ou sem máscara, e identifica o tipo pela
+// quantidade de dígitos.
+func Parse(s string) (Document, error) {
+	d, err := clean(s)
+	if err != nil {
+		return Document{}, err
+	}
+	switch len(d) {
+	case 11:
+		return ParseCPF(d)
+	case 14:
+		return ParseCNPJ(d)
+	}
+	return Document{}, ErrLength
+}
+
+// ParseCPF valida um CPF.
+func ParseCPF(s string) (Document, error) {
+	d, err := clean(s)
+	if err != nil {
+		return Document{}, err
+	}
+	if len(d) != 11 {
+		return Document{}, ErrCPFLength
+	}
+	if repeated(d) || checkDigit(d[:9], 10) != d[9] || checkDigit(d[:10], 11) != d[10] {
+		return Document{}, ErrCPFDigits
+	}
+	return Document{Kind: KindCPF, Digits: d, Display: d[0:3] + "." + d[3:6] + "." + d[6:9] + "-" + d[9:11]}, nil
+}
+
+// ParseCNPJ valida um CNPJ.
+func ParseCNPJ(s string) (Document, error) {
+	d, err := clean(s)
+	if err != nil {
+		return Document{}, err
+	}
+	if len(d) != 14 {
+		return Document{}, ErrCNPJLength
+	}
+	if repeated(d) || cnpjDigit(d[:12]) != d[12] || cnpjDigit(d[:13]) != d[13] {
+		return Document{}, ErrCNPJDigits
+	}
+	return Document{Kind: KindCNPJ, Digits: d, Display: d[0:2] + "." + d[2:5] + "." + d[5:8] + "/" + d[8:12] + "-" + d[12:14]}, nil
+}
+
+// clean aceita só dígitos e a pontuação usual das máscaras.
+func clean(s string) (string, error) {
+	for _, r := range s {
+		if (r < '0' || r > '9') && !strings.ContainsRune(".-/ ", r) {
+			return "", ErrInvalidChar
+		}
+	}
+	return Digits(s), nil
+}
+
+// repeated rejeita sequências como 111.111.111-11, que passam no cálculo.
+func repeated(d string) bool {
+	return strings.Count(d, d[:1]) == len(d)
+}
+
+// checkDigit calcula o dígito do CPF: pesos decrescentes a partir de weight.
+func checkDigit(d string, weight int) byte {
+	sum := 0
+	for i := range d {
+		sum += int(d[i]-'0') * (weight - i)
+	}
+	r := sum * 10 % 11
+	if r == 10 {
+		r = 0
+	}
+	return byte('0' + r)
+}
+
+// cnpjDigit calcula o dígito do CNPJ: pesos de 2 a 9 da direita para a esquerda.
+func cnpjDigit(d string) byte {
+	sum, weight := 0, 2
+	for i := len(d) - 1; i >= 0; i-- {
+		sum += int(d[i]-'0') * weight
+		weight++
+		if weight > 9 {
+			weight = 2
+		}
+	}
+	r := sum % 11
+	if r < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - r)
+}
@@ -0,0 +1,49 @@
+package document
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in          string
+		wantKind    string
+		wantDigits  string
+		wantDisplay string
+		wantErr     error
+	}{
+		{"529.982.247-25", KindCPF, "52998224725", "529.982.247-25", nil},
+		{"52998224725", KindCPF, "52998224725", "529.982.247-25", nil},
+		{" 529 982 247 25 ", KindCPF, "52998224725", "529.982.247-25", nil},
+		{"529.982.247-24", "", "", "", ErrCPFDigits},
+		{"111.111.111-11", "", "", "", ErrCPFDigits},
+		{"11.222.333/0001-81", KindCNPJ, "11222333000181", "11.222.333/0001-81", nil},
+		{"11222333000181", KindCNPJ, "11222333000181", "11.222.333/0001-81", nil},
+		{"11.222.333/0001-80", "", "", "", ErrCNPJDigits},
+		{"1234567", "", "", "", ErrLength},
+		{"529.982.247-2A", "", "", "", ErrInvalidChar},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != tt.wantErr {
+			t.Errorf("Parse(%q) err = %v, want %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got.Kind != tt.wantKind || got.Digits != tt.wantDigits || got.Display != tt.wantDisplay {
+			t.Errorf("Parse(%q) = %+v, want %s %s %s", tt.in, got, tt.wantKind, tt.wantDigits, tt.wantDisplay)
+		}
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	if _, err := ParseCPF("11.222.333/0001-81"); err != ErrCPFLength {
+		t.Errorf("ParseCPF(CNPJ) err = %v, want ErrCPFLength", err)
+	}
+	if _, err := ParseCNPJ("529.982.247-25"); err != ErrCNPJLength {
+		t.Errorf("ParseCNPJ(CPF) err = %v, want ErrCNPJLength", err)
+	}
+}
+
+func TestDigits(t *testing.T) {
+	if got := Digits("529.982.247-25"); got != "52998224725" {
+		t.Errorf("Digits = %s", got)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jules-playground/lms-backend/document"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- CPF / CNPJ ---
+
+// fieldErrors junta os erros de validação por campo, com o nome do campo no
+// JSON, para o front marcar cada campo do formulário.
+type fieldErrors map[string]string
+
+// writeFieldErrors responde 400 para dados inválidos ou 409 para conflito com
+// outro cadastro (ex.: CPF já usado).
+func writeFieldErrors(w http.ResponseWriter, status int, fe fieldErrors) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": "Dados inválidos", "fields": fe})
+}
+
+// normalizeDocument valida o documento e devolve a forma de exibição e a
+// canônica. Documento vazio não é erro: o campo é opcional nos cadastros.
+func normalizeDocument(fe fieldErrors, field string, display, digits *string, parse func(string) (document.Document, error)) {
+	if strings.TrimSpace(*display) == "" {
+		*display, *digits = "", ""
+		return
+	}
+	d, err := parse(*display)
+	if err != nil {
+		fe[field] = err.Error()
+		return
+	}
+	*display, *digits = d.Display, d.Digits
+}
+
+// normalizeDocuments valida o CPF (ou CNPJ, para pessoa jurídica) do cliente.
+func (c *Client) normalizeDocuments() fieldErrors {
+	fe := fieldErrors{}
+	normalizeDocument(fe, "cpf", &c.CPF, &c.CPFDigits, document.Parse)
+	return fe
+}
+
+func (e *BlacklistEntry) normalizeDocuments() fieldErrors {
+	fe := fieldErrors{}
+	normalizeDocument(fe, "cpf", &e.CPF, &e.CPFDigits, document.Parse)
+	return fe
+}
+
+// normalizeDocuments valida o documento do avalista do contrato.
+func (l *Loan) normalizeDocuments() fieldErrors {
+	fe := fieldErrors{}
+	normalizeDocument(fe, "guarantorCPF", &l.GuarantorCPF, &l.GuarantorCPFDigits, document.Parse)
+	return fe
+}
+
+func (s *CompanySettings) normalizeDocuments() fieldErrors {
+	fe := fieldErrors{}
+	normalizeDocument(fe, "company.cnpj", &s.CNPJ, &s.CNPJDigits, document.ParseCNPJ)
+	return fe
+}
+
+// migrateDocuments grava a forma canônica e a de exibição dos documentos já
+// cadastrados. Documentos inválidos ficam como estão (só ganham os dígitos,
+// para as buscas) e são contados no resultado para correção manual.
+func migrateDocuments(ctx context.Context) (string, error) {
+	fields := []struct {
+		label  string
+		coll   *mongo.Collection
+		field  string
+		digits string
+		parse  func(string) (document.Document, error)
+	}{
+		{"cliente(s)", clientCollection, "cpf", "cpfDigits", document.Parse},
+		{"registro(s) da lista negra", blacklistCollection, "cpf", "cpfDigits", document.Parse},
+		{"avalista(s)", loanCollection, "guarantorCPF", "guarantorCPFDigits", document.Parse},
+		{"CNPJ da empresa", settingsCollection, "company.cnpj", "company.cnpjDigits", document.ParseCNPJ},
+	}
+	var done, invalid []string
+	for _, f := range fields {
+		ok, bad, err := normalizeDocumentField(ctx, f.coll, f.field, f.digits, f.parse)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", f.label, err)
+		}
+		done = append(done, fmt.Sprintf("%d %s", ok, f.label))
+		if bad > 0 {
+			invalid = append(invalid, fmt.Sprintf("%d %s", bad, f.label))
+		}
+	}
+	details := "documentos normalizados: " + strings.Join(done, ", ")
+	if len(invalid) > 0 {
+		details += "; inválidos mantidos para correção: " + strings.Join(invalid, ", ")
+	}
+	return details, nil
+}
+
+func normalizeDocumentField(ctx context.Context, coll *mongo.Collection, field, digitsField string, parse func(string) (document.Document, error)) (ok, invalid int, err error) {
+	opts := options.Find().SetProjection(bson.M{field: 1})
+	cursor, err := coll.Find(ctx, bson.M{field: bson.M{"$exists": true, "$nin": bson.A{"", nil}}}, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		raw, _ := cursor.Current.Lookup(strings.Split(field, ".")...).StringValueOK()
+		set := bson.M{digitsField: document.Digits(raw)}
+		if d, err := parse(raw); err == nil {
+			set[field], set[digitsField] = d.Display, d.Digits
+			ok++
+		} else {
+			invalid++
+		}
+		if _, err := coll.UpdateOne(ctx, bson.M{"_id": cursor.Current.Lookup("_id")}, bson.M{"$set": set}); err != nil {
+			return ok, invalid, err
+		}
+	}
+	return ok, invalid, cursor.Err()
+}
@@ -0,0 +1,320 @@
+// Package finance concentra a matemática dos contratos (tabelas de amortização,
+// encargos e cotações) para que o backend seja a única fonte da verdade.
+package finance
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+// Modalidades de amortização aceitas em Loan.InterestType.
+const (
+	TypePrice  = "PRICE"  // Tabela Price: parcela fixa, juros sobre o saldo
+	TypeSAC    = "SAC"    // Amortização constante, parcela decrescente
+	TypeLinear = "LINEAR" // Parcela da Price com capital e juros divididos em partes iguais
+	TypeSimple = "SIMPLE" // Pagamento mínimo: só juros, capital devolvido na última parcela
+)
+
+// Periodicidades aceitas em Loan.Frequency.
+const (
+	FreqDaily   = "DIARIO"
+	FreqWeekly  = "SEMANAL"
+	FreqMonthly = "MENSAL"
+)
+
+// DateLayout é o formato das datas de vencimento gravadas no Loan.
+const DateLayout = "2006-01-02"
+
+var (
+	ErrInvalidAmount       = errors.New("valor do empréstimo deve ser maior que zero")
+	ErrInvalidInstallments = errors.New("quantidade de parcelas deve ser maior que zero")
+	ErrInvalidRate         = errors.New("taxa de juros não pode ser negativa")
+	ErrInvalidType         = errors.New("modalidade de juros desconhecida")
+	ErrInvalidFrequency    = errors.New("periodicidade desconhecida")
+)
+
+// Params descreve um contrato a ser amortizado. InterestRate é a taxa mensal
+// em percentual (ex: 10 para 10% a.m.), como digitada na tela de Billing.
+type Params struct {
+	Amount       float64
+	InterestRate float64
+	Installments int
+	Frequency    string
+	InterestType string
+	FirstDue     time.Time
+	// AdjustDue, quando informado, move vencimentos que caem em dia não útil
+	// (ver DueDates).
+	AdjustDue func(time.Time) time.Time
+	// GraceDays é a carência entre a liberação e o início normal do cronograma;
+	// os juros do período são distribuídos conforme GraceRule (ver SpreadGraceInterest).
+	GraceDays int
+	GraceRule string
+}
+
+// Installment é uma linha da tabela de amortização.
+type Installment struct {
+	Number   int     `json:"number"`
+	DueDate  string  `json:"dueDate"`
+	Payment  float64 `json:"payment"`
+	Interest float64 `json:"interest"`
+	Capital  float64 `json:"capital"`
+	Balance  float64 `json:"balance"`
+}
+
+// Schedule é a tabela completa de um contrato.
+type Schedule struct {
+	InterestType     string        `json:"interestType"`
+	Frequency        string        `json:"frequency"`
+	PeriodicRate     float64       `json:"periodicRate"`
+	InstallmentValue float64       `json:"installmentValue"`
+	TotalInterest    float64       `json:"totalInterest"`
+	TotalPayable     float64       `json:"totalPayable"`
+	GraceInterest    float64       `json:"graceInterest,omitempty"`
+	Installments     []Installment `json:"installments"`
+}
+
+// NormalizeType devolve a modalidade em maiúsculas, assumindo PRICE quando vazia
+// (mesmo padrão do formulário de novo contrato).
+func NormalizeType(t string) string {
+	t = strings.ToUpper(strings.TrimSpace(t))
+	if t == "" {
+		return TypePrice
+	}
+	return t
+}
+
+// NormalizeFrequency devolve a periodicidade em maiúsculas, assumindo MENSAL quando vazia.
+func NormalizeFrequency(f string) string {
+	f = strings.ToUpper(strings.TrimSpace(f))
+	if f == "" {
+		return FreqMonthly
+	}
+	return f
+}
+
+// PeriodicRate converte a taxa mensal (%) para a taxa decimal do período.
+// Segue a convenção comercial já usada no front: semana = mês/4, dia = mês/30.
+func PeriodicRate(monthlyPercent float64, frequency string) float64 {
+	rate := monthlyPercent / 100
+	switch NormalizeFrequency(frequency) {
+	case FreqWeekly:
+		return rate / 4
+	case FreqDaily:
+		return rate / 30
+	default:
+		return rate
+	}
+}
+
+// DueDate devolve o vencimento da parcela n (1-based) a partir do primeiro vencimento.
+// Na periodicidade mensal o dia é preservado e ajustado para o último dia do mês
+// quando necessário (31/01 -> 28/02 -> 31/03).
+func DueDate(first time.Time, frequency string, n int) time.Time {
+	steps := n - 1
+	switch NormalizeFrequency(frequency) {
+	case FreqDaily:
+		return first.AddDate(0, 0, steps)
+	case FreqWeekly:
+		return first.AddDate(0, 0, 7*steps)
+	default:
+		return AddMonths(first, steps)
+	}
+}
+
+// DueDates devolve os n vencimentos a partir do primeiro. Com adjust, cada data
+// calculada passa pelo ajuste (ex: próximo dia útil); na periodicidade diária a
+// parcela seguinte parte da data ajustada, para que duas parcelas não caiam no
+// mesmo dia útil.
+func DueDates(first time.Time, frequency string, n int, adjust func(time.Time) time.Time) []time.Time {
+	dates := make([]time.Time, n)
+	daily := NormalizeFrequency(frequency) == FreqDaily
+	for i := range dates {
+		d := DueDate(first, frequency, i+1)
+		if adjust != nil {
+			if daily && i > 0 {
+				d = dates[i-1].AddDate(0, 0, 1)
+			}
+			d = adjust(d)
+		}
+		dates[i] = d
+	}
+	return dates
+}
+
+// AddMonths soma meses sem o "transbordo" do time.AddDate (31/01 + 1 mês = 03/03).
+func AddMonths(t time.Time, months int) time.Time {
+	y, m, d := t.Date()
+	target := time.Date(y, m+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := target.AddDate(0, 1, -1).Day()
+	if d > last {
+		d = last
+	}
+	return time.Date(target.Year(), target.Month(), d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// PricePayment calcula a parcela fixa da Tabela Price, arredondada ao centavo.
+func PricePayment(amount, rate float64, n int) float64 {
+	if n <= 0 {
+		return 0
+	}
+	if rate == 0 {
+		return Round2(amount / float64(n))
+	}
+	f := math.Pow(1+rate, float64(n))
+	return Round2(amount * rate * f / (f - 1))
+}
+
+// Build gera a tabela de amortização completa. Todos os valores são
+// arredondados ao centavo e a última parcela absorve os resíduos, de modo que
+// a soma do capital amortizado é sempre igual a Amount.
+func Build(p Params) (Schedule, error) {
+	if p.Amount <= 0 {
+		return Schedule{}, ErrInvalidAmount
+	}
+	if p.Installments <= 0 {
+		return Schedule{}, ErrInvalidInstallments
+	}
+	if p.InterestRate < 0 {
+		return Schedule{}, ErrInvalidRate
+	}
+	freq := NormalizeFrequency(p.Frequency)
+	if freq != FreqDaily && freq != FreqWeekly && freq != FreqMonthly {
+		return Schedule{}, ErrInvalidFrequency
+	}
+	kind := NormalizeType(p.InterestType)
+	rate := PeriodicRate(p.InterestRate, freq)
+	n := p.Installments
+
+	// Cálculo interno em centavos para não acumular erro de ponto flutuante.
+	principal := toCents(p.Amount)
+	var rows []Installment
+
+	switch kind {
+	case TypePrice:
+		pmt := toCents(PricePayment(p.Amount, rate, n))
+		balance := principal
+		for i := 1; i <= n; i++ {
+			interest := centsOf(float64(balance) * rate)
+			capital := pmt - interest
+			if i == n || capital > balance {
+				capital = balance
+			}
+			balance -= capital
+			rows = append(rows, row(i, capital, interest, balance))
+		}
+	case TypeSAC:
+		base := principal / int64(n)
+		balance := principal
+		for i := 1; i <= n; i++ {
+			interest := centsOf(float64(balance) * rate)
+			capital := base
+			if i == n {
+				capital = balance
+			}
+			balance -= capital
+			rows = append(rows, row(i, capital, interest, balance))
+		}
+	case TypeLinear:
+		pmt := toCents(PricePayment(p.Amount, rate, n))
+		totalInterest := pmt*int64(n) - principal
+		if totalInterest < 0 {
+			totalInterest = 0
+		}
+		// Capital dividido igualmente; os juros completam a parcela da Price.
+		capBase := principal / int64(n)
+		intBase := pmt - capBase
+		if intBase < 0 {
+			intBase = 0
+		}
+		balance, interestLeft := principal, totalInterest
+		for i := 1; i <= n; i++ {
+			capital, interest := capBase, intBase
+			if interest > interestLeft {
+				interest = interestLeft
+			}
+			if i == n {
+				capital, interest = balance, interestLeft
+			}
+			balance -= capital
+			interestLeft -= interest
+			rows = append(rows, row(i, capital, interest, balance))
+		}
+	case TypeSimple:
+		interest := centsOf(float64(principal) * rate)
+		for i := 1; i <= n; i++ {
+			var capital int64
+			if i == n {
+				capital = principal
+			}
+			rows = append(rows, row(i, capital, interest, principal-capital))
+		}
+	default:
+		return Schedule{}, ErrInvalidType
+	}
+
+	if p.GraceDays < 0 {
+		return Schedule{}, ErrInvalidGraceDays
+	}
+	graceRule, err := NormalizeGraceRule(p.GraceRule)
+	if err != nil {
+		return Schedule{}, err
+	}
+	grace := GraceInterest(p.Amount, p.InterestRate, p.GraceDays)
+	for i, extra := range SpreadGraceInterest(grace, rate, n, graceRule) {
+		rows[i].Interest = Round2(rows[i].Interest + extra)
+		rows[i].Payment = Round2(rows[i].Payment + extra)
+	}
+
+	s := Schedule{
+		InterestType:  kind,
+		Frequency:     freq,
+		PeriodicRate:  rate,
+		GraceInterest: grace,
+		Installments:  rows,
+	}
+	var dates []time.Time
+	if !p.FirstDue.IsZero() {
+		dates = DueDates(p.FirstDue, freq, len(rows), p.AdjustDue)
+	}
+	var totalInterest, totalPayable int64
+	for i := range s.Installments {
+		if dates != nil {
+			s.Installments[i].DueDate = dates[i].Format(DateLayout)
+		}
+		totalInterest += toCents(s.Installments[i].Interest)
+		totalPayable += toCents(s.Installments[i].Payment)
+	}
+	s.InstallmentValue = s.Installments[0].Payment
+	s.TotalInterest = fromCents(totalInterest)
+	s.TotalPayable = fromCents(totalPayable)
+	return s, nil
+}
+
+func row(n int, capital, interest, balance int64) Installment {
+	return Installment{
+		Number:   n,
+		Payment:  fromCents(capital + interest),
+		Interest: fromCents(interest),
+		Capital:  fromCents(capital),
+		Balance:  fromCents(balance),
+	}
+}
+
+// Round2 arredonda ao centavo (meio para cima, como no front).
+func Round2(v float64) float64 {
+	return fromCents(toCents(v))
+}
+
+func toCents(v float64) int64 {
+	return int64(math.Round(v * 100))
+}
+
+func centsOf(v float64) int64 {
+	return int64(math.Round(v))
+}
+
+func fromCents(c int64) float64 {
+	return float64(c) / 100
+}
@@ -0,0 +1,209 @@
+package finance
+
+import (
+	"testing"
+	"time"
+)
+
+type row3 struct {
+	payment, interest, capital, balance float64
+}
+
+func TestBuildKnownTables(t *testing.T) {
+	tests := []struct {
+		name          string
+		params        Params
+		rows          map[int]row3 // linhas conferidas da tabela (1-based)
+		installment   float64
+		totalInterest float64
+	}{
+		{
+			name:   "price 10k a 1% em 12x",
+			params: Params{Amount: 10000, InterestRate: 1, Installments: 12, InterestType: TypePrice},
+			rows: map[int]row3{
+				1:  {888.49, 100.00, 788.49, 9211.51},
+				2:  {888.49, 92.12, 796.37, 8415.14},
+				6:  {888.49, 59.78, 828.71, 5149.20},
+				12: {888.47, 8.80, 879.67, 0},
+			},
+			installment:   888.49,
+			totalInterest: 661.86,
+		},
+		{
+			name:   "price 1000 a 10% em 3x",
+			params: Params{Amount: 1000, InterestRate: 10, Installments: 3, InterestType: TypePrice},
+			rows: map[int]row3{
+				1: {402.11, 100.00, 302.11, 697.89},
+				2: {402.11, 69.79, 332.32, 365.57},
+				3: {402.13, 36.56, 365.57, 0},
+			},
+			installment:   402.11,
+			totalInterest: 206.35,
+		},
+		{
+			name:   "sac 1200 a 2% em 12x",
+			params: Params{Amount: 1200, InterestRate: 2, Installments: 12, InterestType: TypeSAC},
+			rows: map[int]row3{
+				1:  {124, 24, 100, 1100},
+				2:  {122, 22, 100, 1000},
+				7:  {112, 12, 100, 500},
+				12: {102, 2, 100, 0},
+			},
+			installment:   124,
+			totalInterest: 156,
+		},
+		{
+			name:   "sac com resíduo na última parcela",
+			params: Params{Amount: 10000, InterestRate: 1, Installments: 12, InterestType: TypeSAC},
+			rows: map[int]row3{
+				1:  {933.33, 100.00, 833.33, 9166.67},
+				12: {841.70, 8.33, 833.37, 0},
+			},
+			installment:   933.33,
+			totalInterest: 650,
+		},
+		{
+			name:   "linear divide a parcela da price",
+			params: Params{Amount: 10000, InterestRate: 1, Installments: 12, InterestType: TypeLinear},
+			rows: map[int]row3{
+				1:  {888.49, 55.16, 833.33, 9166.67},
+				12: {888.49, 55.12, 833.37, 0},
+			},
+			installment:   888.49,
+			totalInterest: 661.88,
+		},
+		{
+			name:   "simple paga só juros e devolve o capital no fim",
+			params: Params{Amount: 1000, InterestRate: 10, Installments: 3, InterestType: TypeSimple},
+			rows: map[int]row3{
+				1: {100, 100, 0, 1000},
+				3: {1100, 100, 1000, 0},
+			},
+			installment:   100,
+			totalInterest: 300,
+		},
+		{
+			name:   "taxa zero",
+			params: Params{Amount: 100, InterestRate: 0, Installments: 3, InterestType: TypePrice},
+			rows: map[int]row3{
+				1: {33.33, 0, 33.33, 66.67},
+				3: {33.34, 0, 33.34, 0},
+			},
+			installment:   33.33,
+			totalInterest: 0,
+		},
+		{
+			name:   "semanal usa taxa mensal / 4",
+			params: Params{Amount: 1000, InterestRate: 20, Installments: 4, Frequency: FreqWeekly, InterestType: TypeSAC},
+			rows: map[int]row3{
+				1: {300, 50, 250, 750},
+				4: {262.50, 12.50, 250, 0},
+			},
+			installment:   300,
+			totalInterest: 125,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Build(tt.params)
+			if err != nil {
+				t.Fatalf("Build: %v", err)
+			}
+			if len(s.Installments) != tt.params.Installments {
+				t.Fatalf("parcelas = %d, esperado %d", len(s.Installments), tt.params.Installments)
+			}
+			if s.InstallmentValue != tt.installment {
+				t.Errorf("InstallmentValue = %.2f, esperado %.2f", s.InstallmentValue, tt.installment)
+			}
+			if s.TotalInterest != tt.totalInterest {
+				t.Errorf("TotalInterest = %.2f, esperado %.2f", s.TotalInterest, tt.totalInterest)
+			}
+			for n, want := range tt.rows {
+				got := s.Installments[n-1]
+				if got.Payment != want.payment || got.Interest != want.interest || got.Capital != want.capital || got.Balance != want.balance {
+					t.Errorf("parcela %d = %+v, esperado %+v", n, got, want)
+				}
+			}
+			var capital float64
+			for _, in := range s.Installments {
+				capital += in.Capital
+			}
+			if Round2(capital) != tt.params.Amount {
+				t.Errorf("capital amortizado = %.2f, esperado %.2f", capital, tt.params.Amount)
+			}
+		})
+	}
+}
+
+func TestBuildInvalidParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params Params
+		want   error
+	}{
+		{"valor zero", Params{Amount: 0, InterestRate: 1, Installments: 1}, ErrInvalidAmount},
+		{"sem parcelas", Params{Amount: 100, InterestRate: 1}, ErrInvalidInstallments},
+		{"taxa negativa", Params{Amount: 100, InterestRate: -1, Installments: 1}, ErrInvalidRate},
+		{"modalidade", Params{Amount: 100, InterestRate: 1, Installments: 1, InterestType: "XPTO"}, ErrInvalidType},
+		{"periodicidade", Params{Amount: 100, InterestRate: 1, Installments: 1, Frequency: "ANUAL"}, ErrInvalidFrequency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Build(tt.params); err != tt.want {
+				t.Errorf("erro = %v, esperado %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDueDate(t *testing.T) {
+	first := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		frequency string
+		n         int
+		want      string
+	}{
+		{FreqMonthly, 1, "2026-01-31"},
+		{FreqMonthly, 2, "2026-02-28"},
+		{FreqMonthly, 3, "2026-03-31"},
+		{FreqMonthly, 13, "2027-01-31"},
+		{FreqWeekly, 2, "2026-02-07"},
+		{FreqDaily, 2, "2026-02-01"},
+	}
+	for _, tt := range tests {
+		if got := DueDate(first, tt.frequency, tt.n).Format(DateLayout); got != tt.want {
+			t.Errorf("DueDate(%s, %d) = %s, esperado %s", tt.frequency, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestDueDatesAdjusted(t *testing.T) {
+	skipWeekend := func(d time.Time) time.Time {
+		for d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			d = d.AddDate(0, 0, 1)
+		}
+		return d
+	}
+	// 2026-03-06 é sexta-feira.
+	first := time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		frequency string
+		want      []string
+	}{
+		{FreqDaily, []string{"2026-03-06", "2026-03-09", "2026-03-10", "2026-03-11"}},
+		{FreqWeekly, []string{"2026-03-06", "2026-03-13", "2026-03-20", "2026-03-27"}},
+		{FreqMonthly, []string{"2026-03-06", "2026-04-06", "2026-05-06", "2026-06-08"}},
+	}
+	for _, tt := range tests {
+		got := DueDates(first, tt.frequency, len(tt.want), skipWeekend)
+		for i, d := range got {
+			if d.Format(DateLayout) != tt.want[i] {
+				t.Errorf("%s parcela %d = %s, esperado %s", tt.frequency, i+1, d.Format(DateLayout), tt.want[i])
+			}
+		}
+	}
+	if got := DueDates(first, FreqDaily, 2, nil)[1].Format(DateLayout); got != "2026-03-07" {
+		t.Errorf("sem ajuste a diária deve cair no sábado, veio %s", got)
+	}
+}
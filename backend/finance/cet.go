@@ -0,0 +1,59 @@
+package finance
+
+import (
+	"math"
+	"time"
+)
+
+// CET devolve o custo efetivo total anual, em percentual, do fluxo em que o
+// cliente recebe released na data start e paga as parcelas do cronograma nos
+// seus vencimentos. Segue a convenção do Bacen (base 365 dias corridos).
+// Parcelas sem data usam o número do período vezes a duração da periodicidade.
+func CET(released float64, start time.Time, s Schedule) float64 {
+	if released <= 0 || len(s.Installments) == 0 {
+		return 0
+	}
+	step := 30.0
+	switch s.Frequency {
+	case FreqWeekly:
+		step = 7
+	case FreqDaily:
+		step = 1
+	}
+	type flow struct{ years, value float64 }
+	flows := make([]flow, len(s.Installments))
+	for i, in := range s.Installments {
+		days := step * float64(in.Number)
+		if due, err := time.Parse(DateLayout, in.DueDate); err == nil && !start.IsZero() {
+			days = math.Max(1, due.Sub(start).Hours()/24)
+		}
+		flows[i] = flow{years: days / 365, value: in.Payment}
+	}
+	npv := func(rate float64) float64 {
+		v := -released
+		for _, f := range flows {
+			v += f.value / math.Pow(1+rate, f.years)
+		}
+		return v
+	}
+
+	// O valor presente cai com a taxa: bissecção no intervalo (-100%, 10^9%).
+	lo, hi := -0.9999, 1e7
+	if npv(lo) < 0 {
+		return Round2(lo * 100)
+	}
+	for i := 0; i < 300; i++ {
+		mid := (lo + hi) / 2
+		if npv(mid) > 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return Round2((lo + hi) / 2 * 100)
+}
+
+// MonthlyEquivalent converte uma taxa anual (%) na taxa mensal equivalente (%).
+func MonthlyEquivalent(annualPercent float64) float64 {
+	return Round2((math.Pow(1+annualPercent/100, 1.0/12) - 1) * 100)
+}
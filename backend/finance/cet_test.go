@@ -0,0 +1,48 @@
+package finance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCET(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		released float64
+		schedule Schedule
+		want     float64
+	}{
+		{
+			name:     "parcela única em um ano",
+			released: 1000,
+			schedule: Schedule{Installments: []Installment{{Number: 1, DueDate: "2027-01-01", Payment: 1100}}},
+			want:     10,
+		},
+		{
+			name:     "sem juros",
+			released: 1000,
+			schedule: Schedule{Installments: []Installment{{Number: 1, DueDate: "2026-07-01", Payment: 500}, {Number: 2, DueDate: "2027-01-01", Payment: 500}}},
+			want:     0,
+		},
+		{
+			name:     "dois anos",
+			released: 1000,
+			schedule: Schedule{Installments: []Installment{{Number: 1, DueDate: "2028-01-01", Payment: 1210}}},
+			want:     10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CET(tt.released, start, tt.schedule); got != tt.want {
+				t.Errorf("CET = %.2f, esperado %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonthlyEquivalent(t *testing.T) {
+	if got := MonthlyEquivalent(213.84); got != 10 {
+		t.Errorf("MonthlyEquivalent(213.84) = %.2f, esperado 10.00", got)
+	}
+}
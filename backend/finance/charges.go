@@ -0,0 +1,30 @@
+package finance
+
+import "time"
+
+// LateCharge calcula os encargos de atraso de uma parcela: multa única de
+// fineRate% sobre o valor da parcela e mora simples de moraRate% ao dia sobre
+// o valor ainda em aberto. Ambos arredondados ao centavo.
+func LateCharge(installment, open, fineRate, moraRate float64, daysLate int) (fine, mora float64) {
+	if daysLate <= 0 || open <= 0 {
+		return 0, 0
+	}
+	fine = Round2(installment * fineRate / 100)
+	mora = Round2(open * moraRate / 100 * float64(daysLate))
+	return fine, mora
+}
+
+// ProRata devolve a parte de amount proporcional aos dias corridos entre start e
+// at dentro do período [start, end]. Usado para os juros da parcela corrente na
+// quitação antecipada (CDC art. 52 §2º): o restante dos juros é abatido.
+func ProRata(amount float64, start, end, at time.Time) float64 {
+	if !at.After(start) {
+		return 0
+	}
+	if !at.Before(end) {
+		return Round2(amount)
+	}
+	total := end.Sub(start).Hours() / 24
+	elapsed := at.Sub(start).Hours() / 24
+	return Round2(amount * elapsed / total)
+}
@@ -0,0 +1,52 @@
+package finance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLateCharge(t *testing.T) {
+	tests := []struct {
+		name               string
+		installment, open  float64
+		fineRate, moraRate float64
+		days               int
+		wantFine, wantMora float64
+	}{
+		{"em dia", 500, 500, 2, 0.033, 0, 0, 0},
+		{"um dia de atraso", 500, 500, 2, 0.033, 1, 10, 0.17},
+		{"trinta dias", 500, 500, 2, 0.033, 30, 10, 4.95},
+		{"parcial paga reduz a mora", 500, 200, 2, 0.1, 10, 10, 2},
+		{"quitada não gera encargo", 500, 0, 2, 0.1, 10, 0, 0},
+		{"sem taxas", 500, 500, 0, 0, 15, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fine, mora := LateCharge(tt.installment, tt.open, tt.fineRate, tt.moraRate, tt.days)
+			if fine != tt.wantFine || mora != tt.wantMora {
+				t.Errorf("LateCharge = (%.2f, %.2f), esperado (%.2f, %.2f)", fine, mora, tt.wantFine, tt.wantMora)
+			}
+		})
+	}
+}
+
+func TestProRata(t *testing.T) {
+	start := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 4, 10, 0, 0, 0, 0, time.UTC) // 31 dias
+	tests := []struct {
+		name string
+		at   time.Time
+		want float64
+	}{
+		{"antes do período", start.AddDate(0, 0, -1), 0},
+		{"no início", start, 0},
+		{"dez dias", start.AddDate(0, 0, 10), 32.26},
+		{"no vencimento", end, 100},
+		{"depois do vencimento", end.AddDate(0, 0, 5), 100},
+	}
+	for _, tt := range tests {
+		if got := ProRata(100, start, end, tt.at); got != tt.want {
+			t.Errorf("%s: ProRata = %.2f, esperado %.2f", tt.name, got, tt.want)
+		}
+	}
+}
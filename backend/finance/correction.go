@@ -0,0 +1,43 @@
+package finance
+
+import (
+	"math"
+	"time"
+)
+
+// IndexTable guarda a variação mensal de um índice (IPCA, IGP-M, CDI) em
+// percentual, por mês no formato "2006-01".
+type IndexTable map[string]float64
+
+// CorrectionFactor devolve o fator acumulado de correção monetária entre from e
+// to. Cada mês entra com sua variação, pro rata die nos meses parciais; meses sem
+// variação na tabela (ainda não divulgados) não corrigem e são devolvidos em missing.
+func CorrectionFactor(t IndexTable, from, to time.Time) (factor float64, missing []string) {
+	factor = 1
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	for d := from; d.Before(to); {
+		monthStart := time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC)
+		next := monthStart.AddDate(0, 1, 0)
+		end := next
+		if to.Before(end) {
+			end = to
+		}
+		month := d.Format("2006-01")
+		if rate, ok := t[month]; ok {
+			days := end.Sub(d).Hours() / 24
+			inMonth := next.Sub(monthStart).Hours() / 24
+			factor *= math.Pow(1+rate/100, days/inMonth)
+		} else {
+			missing = append(missing, month)
+		}
+		d = end
+	}
+	return factor, missing
+}
+
+// Correct aplica a correção sobre amount e devolve só o acréscimo, arredondado.
+func Correct(t IndexTable, amount float64, from, to time.Time) (float64, []string) {
+	factor, missing := CorrectionFactor(t, from, to)
+	return Round2(amount * (factor - 1)), missing
+}
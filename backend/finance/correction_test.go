@@ -0,0 +1,39 @@
+package finance
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCorrectionFactor(t *testing.T) {
+	table := IndexTable{"2026-01": 1, "2026-02": 2, "2026-03": 0.5}
+	day := func(s string) time.Time {
+		d, _ := time.Parse(DateLayout, s)
+		return d
+	}
+	tests := []struct {
+		name     string
+		from, to string
+		want     float64
+		missing  []string
+	}{
+		{"mês cheio", "2026-01-01", "2026-02-01", 1.01, nil},
+		{"dois meses", "2026-01-01", "2026-03-01", 1.01 * 1.02, nil},
+		{"meio mês de fevereiro", "2026-02-01", "2026-02-15", math.Pow(1.02, 14.0/28), nil},
+		{"mês sem índice", "2026-03-01", "2026-05-01", 1.005, []string{"2026-04"}},
+		{"sem período", "2026-02-10", "2026-02-10", 1, nil},
+		{"datas invertidas", "2026-03-10", "2026-02-10", 1, nil},
+	}
+	for _, tt := range tests {
+		got, missing := CorrectionFactor(table, day(tt.from), day(tt.to))
+		if math.Abs(got-tt.want) > 1e-12 || !reflect.DeepEqual(missing, tt.missing) {
+			t.Errorf("%s: fator = %v %v, esperado %v %v", tt.name, got, missing, tt.want, tt.missing)
+		}
+	}
+
+	if got, _ := Correct(table, 1000, day("2026-01-01"), day("2026-03-01")); got != 30.20 {
+		t.Errorf("Correct = %v, esperado 30.20", got)
+	}
+}
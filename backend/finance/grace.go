@@ -0,0 +1,58 @@
+package finance
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// Regras para os juros da carência (Loan.GraceRule).
+const (
+	GraceCapitalize = "CAPITALIZAR" // juros incorporados ao saldo e pagos com juros ao longo das parcelas
+	GraceDefer      = "DIFERIR"     // juros cobrados sem acréscimo junto com a última parcela
+)
+
+var (
+	ErrInvalidGraceRule = errors.New("regra de carência desconhecida")
+	ErrInvalidGraceDays = errors.New("prazo de carência não pode ser negativo")
+)
+
+// NormalizeGraceRule devolve a regra em maiúsculas, assumindo CAPITALIZAR quando vazia.
+func NormalizeGraceRule(rule string) (string, error) {
+	rule = strings.ToUpper(strings.TrimSpace(rule))
+	switch rule {
+	case "":
+		return GraceCapitalize, nil
+	case GraceCapitalize, GraceDefer:
+		return rule, nil
+	}
+	return "", ErrInvalidGraceRule
+}
+
+// GraceInterest calcula os juros compostos de balance à taxa mensal (%) durante
+// days dias de carência, no mês comercial de 30 dias. Arredondado ao centavo.
+func GraceInterest(balance, monthlyPercent float64, days int) float64 {
+	if balance <= 0 || days <= 0 || monthlyPercent <= 0 {
+		return 0
+	}
+	return Round2(balance * (math.Pow(1+monthlyPercent/100, float64(days)/30) - 1))
+}
+
+// SpreadGraceInterest distribui os juros da carência por n parcelas e devolve o
+// acréscimo de juros de cada uma. CAPITALIZAR soma a cada parcela a prestação da
+// Price desses juros à taxa do período; DIFERIR lança o valor inteiro na última.
+func SpreadGraceInterest(interest, rate float64, n int, rule string) []float64 {
+	extra := make([]float64, n)
+	if n <= 0 || interest <= 0 {
+		return extra
+	}
+	if rule == GraceDefer {
+		extra[n-1] = Round2(interest)
+		return extra
+	}
+	pmt := PricePayment(interest, rate, n)
+	for i := range extra {
+		extra[i] = pmt
+	}
+	return extra
+}
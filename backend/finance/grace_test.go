@@ -0,0 +1,67 @@
+package finance
+
+import "testing"
+
+func TestGraceInterest(t *testing.T) {
+	tests := []struct {
+		name     string
+		balance  float64
+		rate     float64
+		days     int
+		esperado float64
+	}{
+		{"sem carência", 1000, 10, 0, 0},
+		{"um mês", 1000, 10, 30, 100},
+		{"dois meses compostos", 1000, 10, 60, 210},
+		{"quinze dias pró-rata", 1000, 10, 15, 48.81},
+		{"sem juros", 1000, 0, 30, 0},
+	}
+	for _, tt := range tests {
+		if got := GraceInterest(tt.balance, tt.rate, tt.days); got != tt.esperado {
+			t.Errorf("%s: GraceInterest = %.2f, esperado %.2f", tt.name, got, tt.esperado)
+		}
+	}
+}
+
+func TestBuildWithGrace(t *testing.T) {
+	tests := []struct {
+		name       string
+		rule       string
+		acrescimos []float64
+	}{
+		// 100 de juros da carência: Price de 100 a 10% em 3x = 40,21
+		{"capitalizar", GraceCapitalize, []float64{40.21, 40.21, 40.21}},
+		{"diferir", GraceDefer, []float64{0, 0, 100}},
+	}
+	base, err := Build(Params{Amount: 1000, InterestRate: 10, Installments: 3, InterestType: TypePrice})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		s, err := Build(Params{Amount: 1000, InterestRate: 10, Installments: 3, InterestType: TypePrice, GraceDays: 30, GraceRule: tt.rule})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if s.GraceInterest != 100 {
+			t.Errorf("%s: GraceInterest = %.2f, esperado 100", tt.name, s.GraceInterest)
+		}
+		var capital, extras float64
+		for i, in := range s.Installments {
+			extra := Round2(in.Payment - base.Installments[i].Payment)
+			if extra != tt.acrescimos[i] {
+				t.Errorf("%s: parcela %d acréscimo = %.2f, esperado %.2f", tt.name, in.Number, extra, tt.acrescimos[i])
+			}
+			capital += in.Capital
+			extras += extra
+		}
+		if Round2(capital) != 1000 {
+			t.Errorf("%s: capital amortizado = %.2f, esperado 1000", tt.name, capital)
+		}
+		if got := Round2(s.TotalPayable - base.TotalPayable); got != Round2(extras) {
+			t.Errorf("%s: TotalPayable acréscimo = %.2f, esperado %.2f", tt.name, got, extras)
+		}
+	}
+	if _, err := Build(Params{Amount: 1000, InterestRate: 10, Installments: 3, GraceDays: 30, GraceRule: "X"}); err != ErrInvalidGraceRule {
+		t.Errorf("regra inválida: err = %v, esperado ErrInvalidGraceRule", err)
+	}
+}
@@ -0,0 +1,50 @@
+package finance
+
+import (
+	"math"
+	"time"
+)
+
+// Alíquotas padrão de IOF-Crédito para pessoa física (Decreto 6.306/2007).
+const (
+	IOFDailyRate      = 0.0082 // % ao dia sobre o capital de cada parcela
+	IOFAdditionalRate = 0.38   // % fixo sobre o valor da operação
+	IOFMaxDays        = 365    // a parcela diária é limitada a 365 dias
+)
+
+// IOF é o imposto calculado para uma operação de crédito.
+type IOF struct {
+	Daily      float64 `json:"daily"`
+	Additional float64 `json:"additional"`
+	Total      float64 `json:"total"`
+}
+
+// CalcIOF calcula o IOF de uma operação com amortização em parcelas: a alíquota
+// diária incide sobre o capital amortizado em cada parcela pelo número de dias
+// entre a liberação (start) e o vencimento, até IOFMaxDays; a adicional incide uma
+// vez sobre o valor total. Taxas em percentual. Parcelas sem data usam o período
+// da periodicidade, como no CET.
+func CalcIOF(amount float64, start time.Time, s Schedule, dailyRate, additionalRate float64) IOF {
+	step := 30
+	switch s.Frequency {
+	case FreqWeekly:
+		step = 7
+	case FreqDaily:
+		step = 1
+	}
+	var daily int64
+	for _, in := range s.Installments {
+		days := step * in.Number
+		if due, err := time.Parse(DateLayout, in.DueDate); err == nil && !start.IsZero() {
+			days = int(math.Round(due.Sub(start).Hours() / 24))
+		}
+		days = min(max(days, 0), IOFMaxDays)
+		daily += toCents(in.Capital * dailyRate / 100 * float64(days))
+	}
+	additional := toCents(amount * additionalRate / 100)
+	return IOF{
+		Daily:      fromCents(daily),
+		Additional: fromCents(additional),
+		Total:      fromCents(daily + additional),
+	}
+}
@@ -0,0 +1,47 @@
+package finance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalcIOF(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		amount float64
+		rows   []Installment
+		want   IOF
+	}{
+		{
+			// 1000 em 30 dias: 1000 * 0,0082% * 30 = 2,46; adicional 3,80
+			name:   "parcela única",
+			amount: 1000,
+			rows:   []Installment{{Number: 1, DueDate: "2026-01-31", Capital: 1000}},
+			want:   IOF{Daily: 2.46, Additional: 3.80, Total: 6.26},
+		},
+		{
+			// prazo acima de um ano limita a diária em 365 dias (2,993%)
+			name:   "limite de 365 dias",
+			amount: 10000,
+			rows:   []Installment{{Number: 1, DueDate: "2027-07-01", Capital: 10000}},
+			want:   IOF{Daily: 299.30, Additional: 38, Total: 337.30},
+		},
+		{
+			name:   "duas parcelas",
+			amount: 1000,
+			rows: []Installment{
+				{Number: 1, DueDate: "2026-01-31", Capital: 500},
+				{Number: 2, DueDate: "2026-03-02", Capital: 500},
+			},
+			// 500*0,0082%*30 = 1,23 ; 500*0,0082%*60 = 2,46
+			want: IOF{Daily: 3.69, Additional: 3.80, Total: 7.49},
+		},
+	}
+	for _, tt := range tests {
+		got := CalcIOF(tt.amount, start, Schedule{Frequency: FreqMonthly, Installments: tt.rows}, IOFDailyRate, IOFAdditionalRate)
+		if got != tt.want {
+			t.Errorf("%s: CalcIOF = %+v, esperado %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jules-playground/lms-backend/finance"
+	"github.com/jules-playground/lms-backend/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- Cronograma de Parcelas Persistido ---
+
+// Situações de cada parcela do cronograma.
+const (
+	InstallmentPending      = "Pendente"
+	InstallmentPartial      = "Parcial"
+	InstallmentPaid         = "Pago"
+	InstallmentOverdue      = "Atrasado"
+	InstallmentRenegotiated = "Renegociada" // saldo transferido para um acordo
+)
+
+// Tolerância de arredondamento (em centavos) para considerar uma parcela quitada.
+const paidTolerance money.Cents = 1
+
+var saoPaulo = loadSaoPaulo()
+
+func loadSaoPaulo() *time.Location {
+	loc, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		return time.FixedZone("BRT", -3*60*60)
+	}
+	return loc
+}
+
+// today devolve a data corrente (meia-noite) no fuso de São Paulo.
+func today() time.Time {
+	now := time.Now().In(saoPaulo)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, saoPaulo)
+}
+
+// buildLoanSchedule gera o cronograma do contrato a partir do motor de amortização.
+func buildLoanSchedule(l Loan, firstDue time.Time) ([]LoanInstallment, error) {
+	p := loanScheduleParams(l)
+	p.FirstDue = firstDue
+	s, err := finance.Build(p)
+	if err != nil {
+		return nil, err
+	}
+	return installmentsFromSchedule(s), nil
+}
+
+func installmentsFromSchedule(s finance.Schedule) []LoanInstallment {
+	items := make([]LoanInstallment, len(s.Installments))
+	for i, in := range s.Installments {
+		items[i] = LoanInstallment{
+			Number:           in.Number,
+			DueDate:          in.DueDate,
+			ExpectedCapital:  money.FromFloat(in.Capital),
+			ExpectedInterest: money.FromFloat(in.Interest),
+			Status:           InstallmentPending,
+		}
+	}
+	return items
+}
+
+// scheduleFromInstallments troca as linhas da tabela do motor pelas do cronograma
+// gravado, que já trazem as datas ajustadas e os juros acrescidos por pausas, e
+// refaz saldos e totais sobre elas.
+func scheduleFromInstallments(s finance.Schedule, items []LoanInstallment) finance.Schedule {
+	var balance, interest, payable money.Cents
+	for _, in := range items {
+		balance += in.ExpectedCapital
+	}
+	s.Installments = make([]finance.Installment, len(items))
+	for i, in := range items {
+		balance -= in.ExpectedCapital
+		s.Installments[i] = finance.Installment{
+			Number:   in.Number,
+			DueDate:  in.DueDate,
+			Payment:  (in.ExpectedCapital + in.ExpectedInterest).Float(),
+			Interest: in.ExpectedInterest.Float(),
+			Capital:  in.ExpectedCapital.Float(),
+			Balance:  balance.Float(),
+		}
+		interest += in.ExpectedInterest
+		payable += in.ExpectedCapital + in.ExpectedInterest
+	}
+	if len(s.Installments) > 0 {
+		s.InstallmentValue = s.Installments[0].Payment
+	}
+	s.TotalInterest, s.TotalPayable = interest.Float(), payable.Float()
+	return s
+}
+
+// ensureLoanSchedule gera o cronograma de contratos antigos que ainda não o possuem.
+func ensureLoanSchedule(l *Loan) error {
+	if len(l.Schedule) > 0 {
+		return nil
+	}
+	items, err := buildLoanSchedule(*l, loanScheduleParams(*l).FirstDue)
+	if err != nil {
+		return err
+	}
+	l.Schedule = items
+	return nil
+}
+
+// applyPaymentsToSchedule zera os valores pagos e redistribui o histórico do
+// contrato sobre as parcelas (do contrato e dos acordos). Lançamentos antigos, sem
+// alocação, seguem a ordem de vencimento: juros pagos abatem os juros previstos e
+// capital pago abate o capital previsto. O excedente fica na última parcela.
+func applyPaymentsToSchedule(l *Loan, ref time.Time) {
+	if len(l.Schedule) == 0 {
+		return
+	}
+	resetPaid(l.Schedule)
+	for i := range l.Agreements {
+		resetPaid(l.Agreements[i].Schedule)
+	}
+	for _, h := range l.History {
+		// Estornados e seus lançamentos compensatórios se anulam.
+		if h.Reversed || h.ReversalOf != nil {
+			continue
+		}
+		// Pagamentos registrados pelo servidor trazem a alocação exata por parcela.
+		if len(h.Allocations) > 0 {
+			applyAllocations(l, h)
+			continue
+		}
+		allocateToSchedule(l.Schedule, h.InterestPaid, h.Date, false)
+		allocateToSchedule(l.Schedule, h.CapitalPaid, h.Date, true)
+	}
+	refreshInstallmentStatus(l.Schedule, ref)
+	for i := range l.Agreements {
+		refreshAgreementStatus(&l.Agreements[i], ref)
+	}
+	for i := range l.Schedule {
+		if l.Schedule[i].AgreementID != "" {
+			l.Schedule[i].Status = InstallmentRenegotiated
+		}
+	}
+}
+
+func resetPaid(items []LoanInstallment) {
+	for i := range items {
+		items[i].PaidCapital = 0
+		items[i].PaidInterest = 0
+		items[i].PaidFine = 0
+		items[i].PaidMora = 0
+		items[i].PaidCorrection = 0
+		items[i].InterestDiscount = 0
+		items[i].PaidAt = ""
+	}
+}
+
+// installmentsFor devolve o cronograma de destino de uma alocação.
+func installmentsFor(l *Loan, agreementID string) []LoanInstallment {
+	if agreementID == "" {
+		return l.Schedule
+	}
+	for i := range l.Agreements {
+		if l.Agreements[i].ID == agreementID {
+			return l.Agreements[i].Schedule
+		}
+	}
+	return nil
+}
+
+func applyAllocations(l *Loan, h PaymentRecord) {
+	for _, a := range h.Allocations {
+		items := installmentsFor(l, a.Agreement)
+		for i := range items {
+			if items[i].Number != a.Installment {
+				continue
+			}
+			items[i].PaidFine += a.Fine
+			items[i].PaidMora += a.Mora
+			items[i].PaidCorrection += a.Correction
+			items[i].PaidInterest += a.Interest
+			items[i].PaidCapital += a.Capital
+			items[i].InterestDiscount += a.Discount
+			items[i].PaidAt = h.Date
+		}
+	}
+}
+
+func allocateToSchedule(items []LoanInstallment, amount money.Cents, date string, capital bool) {
+	last := len(items) - 1
+	for i := range items {
+		if amount <= 0 {
+			return
+		}
+		paid, expected := &items[i].PaidInterest, items[i].ExpectedInterest
+		if capital {
+			paid, expected = &items[i].PaidCapital, items[i].ExpectedCapital
+		}
+		part := amount
+		if open := expected - *paid; part > open && i < last {
+			part = open
+		}
+		if part <= 0 {
+			continue
+		}
+		*paid += part
+		amount -= part
+		items[i].PaidAt = date
+	}
+}
+
+// refreshInstallmentStatus recalcula a situação das parcelas na data de referência.
+// Vencimento em dia não útil só atrasa depois do dia útil seguinte.
+func refreshInstallmentStatus(items []LoanInstallment, ref time.Time) {
+	for i := range items {
+		in := &items[i]
+		expected := in.ExpectedCapital + in.ExpectedInterest
+		paid := in.PaidCapital + in.PaidInterest + in.InterestDiscount
+		switch {
+		case paid >= expected-paidTolerance:
+			in.Status = InstallmentPaid
+		case isOverdue(in.DueDate, ref):
+			in.Status = InstallmentOverdue
+		case paid > 0:
+			in.Status = InstallmentPartial
+		default:
+			in.Status = InstallmentPending
+		}
+	}
+}
+
+// daysLate conta os dias corridos entre o vencimento e a data de referência.
+func daysLate(dueDate string, ref time.Time) int {
+	due, err := parseLoanDate(dueDate)
+	if err != nil {
+		return 0
+	}
+	y, m, d := ref.Date()
+	refDay := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return int(refDay.Sub(due).Hours() / 24)
+}
+
+// openInstallment é uma parcela ainda devida, do contrato ou de um acordo ativo.
+type openInstallment struct {
+	Agreement string
+	LoanInstallment
+}
+
+// openInstallments lista o que falta pagar: primeiro as parcelas dos acordos ativos,
+// depois as parcelas do contrato que não foram renegociadas.
+func openInstallments(l Loan) []openInstallment {
+	var out []openInstallment
+	for _, a := range l.Agreements {
+		if a.Status != AgreementActive {
+			continue
+		}
+		for _, in := range a.Schedule {
+			if in.Status != InstallmentPaid {
+				out = append(out, openInstallment{Agreement: a.ID, LoanInstallment: in})
+			}
+		}
+	}
+	for _, in := range l.Schedule {
+		if in.Status != InstallmentPaid && in.Status != InstallmentRenegotiated {
+			out = append(out, openInstallment{LoanInstallment: in})
+		}
+	}
+	return out
+}
+
+// refreshLoanState recalcula cronograma, próximo vencimento, parcelas restantes e
+// situação do contrato. Installments guarda o prazo contratado e não é mexido aqui;
+// o que resta em aberto vai para RemainingInstallments.
+func refreshLoanState(l *Loan, ref time.Time) {
+	applyPaymentsToSchedule(l, ref)
+	if len(l.Schedule) == 0 {
+		return
+	}
+	open := openInstallments(*l)
+	for i, in := range open {
+		if i == 0 || in.DueDate < l.NextDue {
+			l.NextDue = in.DueDate
+		}
+	}
+	remaining := len(open)
+	l.RemainingInstallments = &remaining
+	l.Status = deriveLoanStatus(*l)
+}
+
+// migrateRemainingInstallments desfaz o uso antigo de Installments como contador
+// de parcelas restantes: o prazo volta a ser o tamanho do cronograma gravado e o
+// que está em aberto passa para RemainingInstallments.
+func migrateRemainingInstallments(ctx context.Context) (string, error) {
+	cursor, err := loanCollection.Find(ctx, bson.M{"schedule.0": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"id": 1, "schedule": 1, "agreements": 1}))
+	if err != nil {
+		return "", err
+	}
+	defer cursor.Close(ctx)
+	fixed := 0
+	for cursor.Next(ctx) {
+		var l Loan
+		if err := cursor.Decode(&l); err != nil {
+			return "", err
+		}
+		res, err := loanCollection.UpdateOne(ctx, bson.M{"id": l.ID}, bson.M{"$set": bson.M{
+			"installments":          len(l.Schedule),
+			"remainingInstallments": len(openInstallments(l)),
+		}})
+		if err != nil {
+			return "", err
+		}
+		fixed += int(res.ModifiedCount)
+	}
+	return fmt.Sprintf("%d contrato(s) com prazo restaurado a partir do cronograma", fixed), cursor.Err()
+}
+
+// deriveLoanStatus decide a situação do contrato a partir do cronograma já
+// recalculado. Acordo antigo (feito só pelo front) segura o atraso até ser cumprido.
+func deriveLoanStatus(l Loan) string {
+	if l.WriteOff != nil {
+		return LoanWrittenOff
+	}
+	if len(l.Schedule) == 0 {
+		return l.Status
+	}
+	open := openInstallments(l)
+	overdue, inAgreement := false, false
+	for _, in := range open {
+		if in.Status == InstallmentOverdue {
+			overdue = true
+		}
+		if in.Agreement != "" {
+			inAgreement = true
+		}
+	}
+	switch {
+	case len(open) == 0:
+		return "Quitado"
+	case overdue && l.Status == "Acordo" && !inAgreement:
+		return l.Status
+	case overdue:
+		return "Atrasado"
+	case inAgreement:
+		return "Acordo"
+	default:
+		return "Em Dia"
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jules-playground/lms-backend/finance"
+	"github.com/jules-playground/lms-backend/money"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// --- IOF na Originação ---
+
+// iofRates são as alíquotas de IOF em percentual (diária e adicional).
+type iofRates struct {
+	Daily      float64
+	Additional float64
+}
+
+// iofRates devolve as alíquotas configuradas; sem configuração valem as legais.
+func (s SystemSettings) iofRates() iofRates {
+	if s.IOFDailyRate == 0 && s.IOFAdditionalRate == 0 {
+		return iofRates{Daily: finance.IOFDailyRate, Additional: finance.IOFAdditionalRate}
+	}
+	return iofRates{Daily: s.IOFDailyRate, Additional: s.IOFAdditionalRate}
+}
+
+type iofReportRow struct {
+	LoanID        string      `json:"loanId"`
+	Client        string      `json:"client"`
+	StartDate     string      `json:"startDate"`
+	Amount        money.Cents `json:"amount"`
+	IOFDaily      money.Cents `json:"iofDaily"`
+	IOFAdditional money.Cents `json:"iofAdditional"`
+	IOF           money.Cents `json:"iof"`
+	Estimated     bool        `json:"estimated,omitempty"` // contrato anterior ao cálculo de IOF: recalculado agora
+}
+
+type iofReport struct {
+	Month         string         `json:"month"`
+	Count         int            `json:"count"`
+	Amount        money.Cents    `json:"amount"`
+	IOFDaily      money.Cents    `json:"iofDaily"`
+	IOFAdditional money.Cents    `json:"iofAdditional"`
+	IOF           money.Cents    `json:"iof"`
+	Loans         []iofReportRow `json:"loans"`
+}
+
+// iofReportHandler resume o IOF dos contratos liberados no mês (?month=AAAA-MM,
+// padrão mês corrente) para a contabilidade. Contratos antigos, sem IOF gravado,
+// são recalculados com as alíquotas atuais e marcados como estimados.
+func iofReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		month = today().Format("2006-01")
+	}
+	if _, err := time.Parse("2006-01", month); err != nil {
+		http.Error(w, "Mês inválido, use AAAA-MM", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := loanCollection.Find(ctx, bson.M{"startDate": bson.M{"$regex": "^" + month}})
+	if err != nil {
+		http.Error(w, "Erro ao buscar contratos", http.StatusInternalServerError)
+		return
+	}
+	var loans []Loan
+	cursor.All(ctx, &loans)
+
+	rates := loadSettings(ctx).System.iofRates()
+	rep := iofReport{Month: month, Loans: []iofReportRow{}}
+	for _, l := range loans {
+		row := iofReportRow{
+			LoanID:        l.ID,
+			Client:        l.Client,
+			StartDate:     l.StartDate,
+			Amount:        l.Amount,
+			IOFDaily:      l.IOFDaily,
+			IOFAdditional: l.IOFAdditional,
+			IOF:           l.IOF,
+		}
+		if l.IOF == 0 {
+			if iof, ok := estimateLoanIOF(l, rates); ok {
+				row.IOFDaily, row.IOFAdditional, row.IOF = iof.IOFDaily, iof.IOFAdditional, iof.IOF
+				row.Estimated = true
+			}
+		}
+		rep.Count++
+		rep.Amount += row.Amount
+		rep.IOFDaily += row.IOFDaily
+		rep.IOFAdditional += row.IOFAdditional
+		rep.IOF += row.IOF
+		rep.Loans = append(rep.Loans, row)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rep)
+}
+
+// estimateLoanIOF recalcula o IOF de um contrato a partir do cronograma gravado.
+func estimateLoanIOF(l Loan, rates iofRates) (Loan, bool) {
+	start, err := parseLoanDate(l.StartDate)
+	if err != nil || ensureLoanSchedule(&l) != nil {
+		return l, false
+	}
+	s := finance.Schedule{Frequency: finance.NormalizeFrequency(l.Frequency)}
+	for _, in := range l.Schedule {
+		s.Installments = append(s.Installments, finance.Installment{Number: in.Number, DueDate: in.DueDate, Capital: in.ExpectedCapital.Float()})
+	}
+	iof := finance.CalcIOF(l.Amount.Float(), start, s, rates.Daily, rates.Additional)
+	l.IOFDaily = money.FromFloat(iof.Daily)
+	l.IOFAdditional = money.FromFloat(iof.Additional)
+	l.IOF = money.FromFloat(iof.Total)
+	return l, true
+}
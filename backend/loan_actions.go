@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jules-playground/lms-backend/finance"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// --- Sub-rotas de Empréstimos (/api/loans/{id}/...) ---
+
+// O ID do contrato gerado pelo front contém barra ("01/2026"), então a ação é
+// localizada pelo primeiro segmento conhecido e tudo antes dele é o ID.
+var loanActions = map[string]bool{
+	"schedule":     true,
+	"installments": true,
+	"payments":     true,
+	"payoff":       true,
+	"agreements":   true,
+	// GET cota a quitação antecipada, POST registra
+	"settlement-quote": true,
+	"refinance":        true,
+	"payment-holiday":  true,
+	"write-off":        true, // somente ADMIN
+	"recoveries":       true,
+}
+
+func splitLoanPath(path string) (string, []string) {
+	rest := strings.Trim(strings.TrimPrefix(path, "/api/loans/"), "/")
+	parts := strings.Split(rest, "/")
+	for i, p := range parts {
+		if i > 0 && loanActions[p] {
+			return strings.Join(parts[:i], "/"), parts[i:]
+		}
+	}
+	return rest, nil
+}
+
+func loanActionHandler(w http.ResponseWriter, r *http.Request, id string, action []string) {
+	switch action[0] {
+	case "schedule":
+		loanScheduleHandler(w, r, id)
+	case "installments":
+		loanInstallmentsHandler(w, r, id)
+	case "payments":
+		loanPaymentsHandler(w, r, id, action)
+	case "payoff":
+		loanPayoffHandler(w, r, id)
+	case "agreements":
+		loanAgreementsHandler(w, r, id)
+	case "settlement-quote":
+		loanSettlementHandler(w, r, id)
+	case "refinance":
+		loanRefinanceHandler(w, r, id)
+	case "payment-holiday":
+		loanPaymentHolidayHandler(w, r, id)
+	case "write-off":
+		loanWriteOffHandler(w, r, id)
+	case "recoveries":
+		loanRecoveriesHandler(w, r, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+var errLoanNotFound = errors.New("Contrato não encontrado")
+
+func findLoan(ctx context.Context, id string) (Loan, error) {
+	var l Loan
+	err := loanCollection.FindOne(ctx, bson.M{"id": id}).Decode(&l)
+	if err == mongo.ErrNoDocuments {
+		return l, errLoanNotFound
+	}
+	return l, err
+}
+
+func loadSettings(ctx context.Context) Settings {
+	var s Settings
+	settingsCollection.FindOne(ctx, bson.M{}).Decode(&s)
+	return s
+}
+
+// parseLoanDate aceita tanto "2006-01-02" quanto ISO completo ("2006-01-02T15:04:05Z").
+func parseLoanDate(s string) (time.Time, error) {
+	if len(s) > 10 {
+		s = s[:10]
+	}
+	return time.Parse(finance.DateLayout, s)
+}
+
+// loanScheduleParams monta os parâmetros do motor de amortização a partir do contrato.
+// O primeiro vencimento vem do cronograma gravado; sem ele, assume um período
+// após o StartDate, mesma regra do formulário de novo contrato.
+func loanScheduleParams(l Loan) finance.Params {
+	p := finance.Params{
+		Amount:       l.Amount.Float(),
+		InterestRate: l.InterestRate,
+		Installments: l.Installments,
+		Frequency:    l.Frequency,
+		InterestType: l.InterestType,
+		AdjustDue:    dueDateAdjuster(l.DueDateRule),
+		GraceDays:    l.GraceDays,
+		GraceRule:    l.GraceRule,
+	}
+	if len(l.Schedule) > 0 {
+		if first, err := parseLoanDate(l.Schedule[0].DueDate); err == nil {
+			p.FirstDue = first
+			p.Installments = len(l.Schedule)
+			return p
+		}
+	}
+	if start, err := parseLoanDate(l.StartDate); err == nil {
+		p.FirstDue = finance.DueDate(start.AddDate(0, 0, l.GraceDays), l.Frequency, 2)
+	}
+	return p
+}
+
+func loanScheduleHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	l, err := findLoan(ctx, id)
+	if err == errLoanNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Erro ao buscar contrato", http.StatusInternalServerError)
+		return
+	}
+
+	schedule, err := finance.Build(loanScheduleParams(l))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	// O cronograma gravado prevalece: datas (feriados locais podem ter mudado
+	// depois) e juros acrescidos por pausas de pagamento.
+	if len(l.Schedule) > 0 {
+		schedule = scheduleFromInstallments(schedule, l.Schedule)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+func loanInstallmentsHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	l, err := findLoan(ctx, id)
+	if err == errLoanNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Erro ao buscar contrato", http.StatusInternalServerError)
+		return
+	}
+	if err := ensureLoanSchedule(&l); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	applyPaymentsToSchedule(&l, today())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l.Schedule)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jules-playground/lms-backend/finance"
+)
+
+// --- Edição de Contrato (PUT /api/loans/{id}) ---
+
+// O PUT só altera dados cadastrais. Histórico, cronograma, acordos, totais e
+// situação são do servidor e mudam pelas ações próprias (pagamentos, estornos,
+// acordos, pausa, baixa). As condições do contrato só mudam antes de qualquer
+// pagamento, recalculando o cronograma.
+
+var (
+	errLoanTermsLocked   = errors.New("Valor, taxa, prazo e datas só podem ser alterados antes do primeiro pagamento ou pausa")
+	errInvalidPromiseDue = errors.New("Informe o novo vencimento do acordo")
+)
+
+// invalidTermsError embrulha a recusa do motor de cálculo ao recotar o contrato.
+type invalidTermsError struct{ error }
+
+// loanTermsChanged diz se o pedido mexe nas condições que geram o cronograma.
+// Campos de texto vazios valem como "sem alteração".
+func loanTermsChanged(cur, req Loan) bool {
+	return req.Amount != cur.Amount || req.InterestRate != cur.InterestRate ||
+		req.Installments != cur.Installments || req.StartDate != cur.StartDate || req.NextDue != cur.NextDue ||
+		(req.Frequency != "" && req.Frequency != cur.Frequency) ||
+		(req.InterestType != "" && req.InterestType != cur.InterestType) ||
+		(req.DueDateRule != "" && req.DueDateRule != cur.DueDateRule) ||
+		(req.GraceRule != "" && (req.GraceRule != cur.GraceRule || req.GraceDays != cur.GraceDays))
+}
+
+// loanHasMovements diz se o contrato já recebeu, renegociou, teve parcelas
+// pausadas ou foi baixado. Recotar depois de uma pausa apagaria as parcelas
+// empurradas para o fim.
+func loanHasMovements(l Loan) bool {
+	if l.TotalPaidCapital+l.TotalPaidInterest+l.TotalPaidCharges > 0 || len(l.Agreements) > 0 || l.WriteOff != nil {
+		return true
+	}
+	for _, h := range l.History {
+		if h.Type == "Pausa" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyLoanEdit copia do pedido para o contrato gravado só o que a edição pode mudar.
+func applyLoanEdit(ctx context.Context, l *Loan, req Loan, user string) error {
+	before := *l
+	if req.Client != "" {
+		l.Client = req.Client
+	}
+	l.FineRate, l.MoraInterestRate = req.FineRate, req.MoraInterestRate
+	l.ClientBank, l.PaymentMethod, l.Justification = req.ClientBank, req.PaymentMethod, req.Justification
+	l.GuarantorName, l.GuarantorCPF, l.GuarantorCPFDigits, l.GuarantorAddress = req.GuarantorName, req.GuarantorCPF, req.GuarantorCPFDigits, req.GuarantorAddress
+	l.Product, l.AffiliateNotes = req.Product, req.AffiliateNotes
+	// Sem vínculo com afiliado cadastrado a comissão continua sendo digitada.
+	if l.AffiliateCommission == nil {
+		l.AffiliateName, l.AffiliateFee = req.AffiliateName, req.AffiliateFee
+	}
+	if req.CorrectionIndex != l.CorrectionIndex {
+		l.CorrectionIndex = ""
+		if req.CorrectionIndex != "" {
+			index, ok := normalizeIndex(req.CorrectionIndex)
+			if !ok {
+				return errInvalidIndex
+			}
+			l.CorrectionIndex = index
+		}
+	}
+
+	if loanTermsChanged(*l, req) {
+		if loanHasMovements(*l) {
+			return errLoanTermsLocked
+		}
+		l.Amount, l.InterestRate, l.Installments = req.Amount, req.InterestRate, req.Installments
+		l.StartDate, l.NextDue = req.StartDate, req.NextDue
+		if req.Frequency != "" {
+			l.Frequency = req.Frequency
+		}
+		if req.InterestType != "" {
+			l.InterestType = req.InterestType
+		}
+		if req.DueDateRule != "" {
+			l.DueDateRule = req.DueDateRule
+		}
+		if req.GraceRule != "" {
+			l.GraceDays, l.GraceRule = req.GraceDays, req.GraceRule
+		}
+		if err := prepareNewLoan(ctx, l); err != nil {
+			return invalidTermsError{err}
+		}
+		refreshLoanState(l, today())
+	}
+	// Valor maior ou contrato passado para outro cliente: confere o limite de novo.
+	if l.Amount != before.Amount || l.Client != before.Client {
+		l.CreditLimitOverride = req.CreditLimitOverride
+		if err := checkCreditLimit(ctx, l, user); err != nil {
+			return err
+		}
+	}
+
+	if req.AgreementValue != l.AgreementValue {
+		return applyPromise(l, req, user)
+	}
+	return nil
+}
+
+// applyPromise registra (ou desfaz, com valor zero) o acordo simples da tela de
+// cobrança: novo vencimento com um valor extra. Diferente do acordo com
+// cronograma próprio (/agreements), não mexe nas parcelas.
+func applyPromise(l *Loan, req Loan, user string) error {
+	if l.WriteOff != nil {
+		return errLoanWrittenOff
+	}
+	now := time.Now()
+	rec := PaymentRecord{
+		Date:            now.Format(time.RFC3339),
+		Type:            "Acordo",
+		RegisteredAt:    now.Format(time.RFC3339),
+		OriginalDueDate: l.NextDue,
+		User:            user,
+	}
+	if req.AgreementValue <= 0 {
+		// Volta o vencimento para o de antes do último acordo simples.
+		for i := len(l.History) - 1; i >= 0; i-- {
+			if h := l.History[i]; h.Type == "Acordo" && h.Amount == 0 && h.OriginalDueDate != "" {
+				l.NextDue = h.OriginalDueDate
+				break
+			}
+		}
+		rec.Note = fmt.Sprintf("ACORDO DESFEITO: valor extra de R$ %s cancelado; vencimento volta para %s.", l.AgreementValue, l.NextDue)
+		l.AgreementValue, l.Status = 0, "Em Dia"
+		if len(l.Schedule) > 0 {
+			l.Status = deriveLoanStatus(*l)
+		}
+		l.History = append(l.History, rec)
+		return nil
+	}
+	due, err := parseLoanDate(req.AgreementDate)
+	if err != nil {
+		return errInvalidPromiseDue
+	}
+	rec.Note = fmt.Sprintf("ACORDO: Vencimento alterado de %s para %s com valor EXTRA de R$ %s.", l.NextDue, due.Format(finance.DateLayout), req.AgreementValue)
+	l.History = append(l.History, rec)
+	l.Status, l.NextDue = "Acordo", due.Format(finance.DateLayout)
+	l.AgreementDate, l.AgreementValue = l.NextDue, req.AgreementValue
+	return nil
+}
+
+// editLoan aplica a edição sobre a versão gravada. O pedido precisa trazer a
+// versão que o usuário editou; se outra operação gravou antes, devolve conflito.
+func editLoan(ctx context.Context, id string, req Loan, user string) (Loan, error) {
+	l, err := findLoan(ctx, id)
+	if err != nil {
+		return l, err
+	}
+	if req.Version != l.Version {
+		return l, errLoanConflict
+	}
+	if err := applyLoanEdit(ctx, &l, req, user); err != nil {
+		return l, err
+	}
+	if err := ensureLoanSchedule(&l); err != nil {
+		return l, err
+	}
+	applyPaymentsToSchedule(&l, today())
+	if err := saveLoanVersioned(ctx, &l); err != nil {
+		return l, err
+	}
+	return l, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// --- Rotina de Situação dos Contratos ---
+
+// Situações em que o contrato já foi encerrado e não é mais reavaliado.
+var closedLoanStatuses = []string{"Pago", "Quitado", LoanWrittenOff}
+
+// StartLoanStatusRoutine reavalia os contratos em aberto logo após a subida do
+// servidor e depois todo dia às 00:30 (horário de São Paulo).
+func StartLoanStatusRoutine() {
+	go func() {
+		time.Sleep(10 * time.Second)
+		performLoanStatusUpdate()
+		for {
+			now := time.Now().In(saoPaulo)
+			nextRun := time.Date(now.Year(), now.Month(), now.Day(), 0, 30, 0, 0, saoPaulo)
+			if now.After(nextRun) {
+				nextRun = nextRun.Add(24 * time.Hour)
+			}
+			time.Sleep(time.Until(nextRun))
+			performLoanStatusUpdate()
+		}
+	}()
+}
+
+// performLoanStatusUpdate recalcula o cronograma de cada contrato em aberto e
+// grava apenas a situação (parcelas e contrato). Vencimento e fator de parcelas
+// continuam com quem registra os pagamentos.
+func performLoanStatusUpdate() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cursor, err := loanCollection.Find(ctx, bson.M{"status": bson.M{"$nin": closedLoanStatuses}})
+	if err != nil {
+		log.Println("⚠️ Rotina de status:", err)
+		return
+	}
+	var loans []Loan
+	if err := cursor.All(ctx, &loans); err != nil {
+		log.Println("⚠️ Rotina de status:", err)
+		return
+	}
+
+	ref := today()
+	changed := 0
+	for _, l := range loans {
+		if err := ensureLoanSchedule(&l); err != nil {
+			continue
+		}
+		before := installmentStatuses(l.Schedule)
+		applyPaymentsToSchedule(&l, ref)
+		previous := l.Status
+		l.Status = deriveLoanStatus(l)
+		if l.Status == previous && installmentStatuses(l.Schedule) == before {
+			continue
+		}
+		if err := saveLoanVersioned(ctx, &l); err != nil {
+			// Contrato alterado durante a rotina: fica para a próxima execução.
+			continue
+		}
+		if l.Status != previous {
+			changed++
+			logSysAction("STATUS AUTOMÁTICO", fmt.Sprintf("Contrato %s (%s): %s → %s", l.ID, l.Client, previous, l.Status))
+		}
+	}
+	logSysAction("ROTINA DE STATUS", fmt.Sprintf("%d contratos avaliados, %d alterados.", len(loans), changed))
+}
+
+func installmentStatuses(items []LoanInstallment) string {
+	var b strings.Builder
+	for _, in := range items {
+		b.WriteString(in.Status)
+		b.WriteByte('|')
+	}
+	return b.String()
+}
@@ -16,6 +16,9 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jules-playground/lms-backend/calendar"
+	"github.com/jules-playground/lms-backend/commission"
+	"github.com/jules-playground/lms-backend/money"
 	"github.com/rs/cors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -94,12 +97,35 @@ func adminMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "username", claims.Username)))
 	}
 }
 
 // --- Helpers de Segurança ---
 
+// currentUser devolve o usuário gravado no contexto pelo authMiddleware.
+func currentUser(r *http.Request) string {
+	if u, ok := r.Context().Value("username").(string); ok && u != "" {
+		return u
+	}
+	return "Sistema"
+}
+
+// isAdminUser confere o perfil do usuário, para rotas em que só parte das
+// operações é restrita a administradores.
+func isAdminUser(ctx context.Context, username string) bool {
+	return userRole(ctx, username) == "ADMIN"
+}
+
+// userRole devolve o perfil do usuário em maiúsculas (vazio se não existir).
+func userRole(ctx context.Context, username string) string {
+	var user User
+	if err := userCollection.FindOne(ctx, bson.M{"username": username}).Decode(&user); err != nil {
+		return ""
+	}
+	return strings.ToUpper(user.Role)
+}
+
 func hashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 12)
 	return string(bytes), err
@@ -130,6 +156,25 @@ func logAction(action string, details string) {
 	}
 }
 
+// logUserAction grava a auditoria em nome do usuário autenticado.
+func logUserAction(action string, user string, details string) {
+	fmt.Printf("\033[32m[AUDITORIA %s]\033[0m %s (%s) - %s\n", time.Now().Format("15:04:05"), action, user, details)
+	if logCollection != nil {
+		entry := LogEntry{
+			ID:        primitive.NewObjectID().Hex(),
+			Action:    action,
+			User:      user,
+			Details:   details,
+			Timestamp: time.Now(),
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			logCollection.InsertOne(ctx, entry)
+		}()
+	}
+}
+
 func logSysAction(action string, details string) {
 	if logCollection != nil {
 		entry := LogEntry{
@@ -213,18 +258,34 @@ type User struct {
 }
 
 type CompanySettings struct {
-	Name    string `json:"name" bson:"name"`
-	CNPJ    string `json:"cnpj" bson:"cnpj"`
-	PixKey  string `json:"pixKey" bson:"pixKey"`
-	Email   string `json:"email" bson:"email"`
-	Phone   string `json:"phone" bson:"phone"`
-	Address string `json:"address" bson:"address"`
+	Name       string `json:"name" bson:"name"`
+	CNPJ       string `json:"cnpj" bson:"cnpj"`
+	CNPJDigits string `json:"cnpjDigits,omitempty" bson:"cnpjDigits,omitempty"` // forma canônica, só dígitos
+	PixKey     string `json:"pixKey" bson:"pixKey"`
+	Email      string `json:"email" bson:"email"`
+	Phone      string `json:"phone" bson:"phone"`
+	Address    string `json:"address" bson:"address"`
 }
 
 type SystemSettings struct {
 	AutoBackup   bool `json:"autoBackup" bson:"autoBackup"`
 	RequireLogin bool `json:"requireLogin" bson:"requireLogin"`
 	WarningDays  int  `json:"warningDays" bson:"warningDays"`
+	// Ordem de abatimento dos pagamentos (fine, mora, correction, interest, capital)
+	PaymentAllocationOrder []string `json:"paymentAllocationOrder,omitempty" bson:"paymentAllocationOrder,omitempty"`
+	// Feriados municipais/estaduais, somados aos nacionais no calendário de vencimentos
+	// (só mudam por /api/settings/holidays)
+	LocalHolidays []calendar.Holiday `json:"localHolidays,omitempty" bson:"localHolidays,omitempty"`
+	// Alíquotas de IOF em % (zero usa as alíquotas legais de pessoa física)
+	IOFDailyRate      float64 `json:"iofDailyRate,omitempty" bson:"iofDailyRate,omitempty"`
+	IOFAdditionalRate float64 `json:"iofAdditionalRate,omitempty" bson:"iofAdditionalRate,omitempty"`
+	// Exige proposta aprovada (/api/loan-applications) para criar contratos.
+	// Ausente vale como desligado; só administradores mudam.
+	RequireLoanApproval *bool `json:"requireLoanApproval,omitempty" bson:"requireLoanApproval,omitempty"`
+	// Modelos de checklist de aprovação (só mudam por /api/settings/checklists)
+	ChecklistTemplates []ChecklistTemplate `json:"checklistTemplates,omitempty" bson:"checklistTemplates,omitempty"`
+	// BLOQUEAR (padrão) ou SINALIZAR contratos acima do limite de crédito do cliente
+	CreditLimitMode string `json:"creditLimitMode,omitempty" bson:"creditLimitMode,omitempty"`
 }
 
 type Settings struct {
@@ -233,47 +294,137 @@ type Settings struct {
 	System  SystemSettings  `json:"system" bson:"system"`
 }
 
+// PaymentAllocation registra quanto de um pagamento foi para cada parcela.
+type PaymentAllocation struct {
+	Agreement   string      `json:"agreement,omitempty" bson:"agreement,omitempty"` // parcela de acordo quando preenchido
+	Installment int         `json:"installment" bson:"installment"`
+	Fine        money.Cents `json:"fine,omitempty" bson:"fine,omitempty"`
+	Mora        money.Cents `json:"mora,omitempty" bson:"mora,omitempty"`
+	Correction  money.Cents `json:"correction,omitempty" bson:"correction,omitempty"` // correção monetária por índice
+	Interest    money.Cents `json:"interest,omitempty" bson:"interest,omitempty"`
+	Capital     money.Cents `json:"capital,omitempty" bson:"capital,omitempty"`
+	Discount    money.Cents `json:"discount,omitempty" bson:"discount,omitempty"` // juros futuros abatidos na quitação antecipada
+}
+
 type PaymentRecord struct {
-	Date            string  `json:"date" bson:"date"`
-	Amount          float64 `json:"amount" bson:"amount"`
-	CapitalPaid     float64 `json:"capitalPaid" bson:"capitalPaid"`
-	InterestPaid    float64 `json:"interestPaid" bson:"interestPaid"`
-	Type            string  `json:"type" bson:"type"`
-	Note            string  `json:"note" bson:"note"`
-	RegisteredAt    string  `json:"registeredAt" bson:"registeredAt"`
-	OriginalDueDate string  `json:"originalDueDate,omitempty" bson:"originalDueDate,omitempty"`
+	Date            string              `json:"date" bson:"date"`
+	Amount          money.Cents         `json:"amount" bson:"amount"`
+	CapitalPaid     money.Cents         `json:"capitalPaid" bson:"capitalPaid"`
+	InterestPaid    money.Cents         `json:"interestPaid" bson:"interestPaid"`
+	FinePaid        money.Cents         `json:"finePaid,omitempty" bson:"finePaid,omitempty"`
+	MoraPaid        money.Cents         `json:"moraPaid,omitempty" bson:"moraPaid,omitempty"`
+	CorrectionPaid  money.Cents         `json:"correctionPaid,omitempty" bson:"correctionPaid,omitempty"`
+	Type            string              `json:"type" bson:"type"`
+	Note            string              `json:"note" bson:"note"`
+	RegisteredAt    string              `json:"registeredAt" bson:"registeredAt"`
+	OriginalDueDate string              `json:"originalDueDate,omitempty" bson:"originalDueDate,omitempty"`
+	Allocations     []PaymentAllocation `json:"allocations,omitempty" bson:"allocations,omitempty"`
+	RequestID       string              `json:"requestId,omitempty" bson:"requestId,omitempty"`
+	User            string              `json:"user,omitempty" bson:"user,omitempty"`
+	// Estorno: o original fica marcado e um lançamento compensatório aponta para ele
+	Reversed   bool   `json:"reversed,omitempty" bson:"reversed,omitempty"`
+	ReversalOf *int   `json:"reversalOf,omitempty" bson:"reversalOf,omitempty"`
+	Reason     string `json:"reason,omitempty" bson:"reason,omitempty"`
+}
+
+// LoanInstallment é uma parcela do cronograma persistido no contrato.
+type LoanInstallment struct {
+	Number           int         `json:"number" bson:"number"`
+	DueDate          string      `json:"dueDate" bson:"dueDate"`
+	ExpectedCapital  money.Cents `json:"expectedCapital" bson:"expectedCapital"`
+	ExpectedInterest money.Cents `json:"expectedInterest" bson:"expectedInterest"`
+	PaidCapital      money.Cents `json:"paidCapital" bson:"paidCapital"`
+	PaidInterest     money.Cents `json:"paidInterest" bson:"paidInterest"`
+	PaidFine         money.Cents `json:"paidFine,omitempty" bson:"paidFine,omitempty"`
+	PaidMora         money.Cents `json:"paidMora,omitempty" bson:"paidMora,omitempty"`
+	PaidCorrection   money.Cents `json:"paidCorrection,omitempty" bson:"paidCorrection,omitempty"`
+	InterestDiscount money.Cents `json:"interestDiscount,omitempty" bson:"interestDiscount,omitempty"`
+	PaidAt           string      `json:"paidAt,omitempty" bson:"paidAt,omitempty"`
+	Status           string      `json:"status" bson:"status"`
+	AgreementID      string      `json:"agreementId,omitempty" bson:"agreementId,omitempty"` // renegociada neste acordo
+}
+
+// Agreement é um acordo de renegociação: congela o saldo vencido do contrato
+// e o substitui por um novo plano de parcelas.
+type Agreement struct {
+	ID                  string            `json:"id" bson:"id"`
+	CreatedAt           string            `json:"createdAt" bson:"createdAt"`
+	CreatedBy           string            `json:"createdBy" bson:"createdBy"`
+	Status              string            `json:"status" bson:"status"`
+	FrozenCapital       money.Cents       `json:"frozenCapital" bson:"frozenCapital"`
+	FrozenInterest      money.Cents       `json:"frozenInterest" bson:"frozenInterest"`
+	FrozenFine          money.Cents       `json:"frozenFine" bson:"frozenFine"`
+	FrozenMora          money.Cents       `json:"frozenMora" bson:"frozenMora"`
+	FrozenCorrection    money.Cents       `json:"frozenCorrection,omitempty" bson:"frozenCorrection,omitempty"`
+	CorrectionIndex     string            `json:"correctionIndex,omitempty" bson:"correctionIndex,omitempty"` // índice que corrige as parcelas desde a criação
+	ChargesDiscountRate float64           `json:"chargesDiscountRate" bson:"chargesDiscountRate"`
+	Discount            money.Cents       `json:"discount" bson:"discount"`
+	Total               money.Cents       `json:"total" bson:"total"`
+	InstallmentValue    money.Cents       `json:"installmentValue" bson:"installmentValue"`
+	Frequency           string            `json:"frequency" bson:"frequency"`
+	CoveredInstallments []int             `json:"coveredInstallments" bson:"coveredInstallments"`
+	Schedule            []LoanInstallment `json:"schedule" bson:"schedule"`
+	Note                string            `json:"note,omitempty" bson:"note,omitempty"`
 }
 
 type Loan struct {
-	ID                  string          `json:"id" bson:"id"`
-	Client              string          `json:"client" bson:"client"`
-	Amount              float64         `json:"amount" bson:"amount"`
-	Installments        int             `json:"installments" bson:"installments"`
-	InterestRate        float64         `json:"interestRate" bson:"interestRate"`
-	StartDate           string          `json:"startDate" bson:"startDate"`
-	NextDue             string          `json:"nextDue" bson:"nextDue"`
-	Status              string          `json:"status" bson:"status"`
-	InstallmentValue    float64         `json:"installmentValue" bson:"installmentValue"`
-	FineRate            float64         `json:"fineRate" bson:"fineRate"`
-	MoraInterestRate    float64         `json:"moraInterestRate" bson:"moraInterestRate"`
-	ClientBank          string          `json:"clientBank" bson:"clientBank"`
-	PaymentMethod       string          `json:"paymentMethod" bson:"paymentMethod"`
-	Justification       string          `json:"justification,omitempty" bson:"justification,omitempty"`
-	ChecklistAtApproval []string        `json:"checklistAtApproval,omitempty" bson:"checklistAtApproval,omitempty"`
-	TotalPaidInterest   float64         `json:"totalPaidInterest" bson:"totalPaidInterest"`
-	TotalPaidCapital    float64         `json:"totalPaidCapital" bson:"totalPaidCapital"`
-	History             []PaymentRecord `json:"history" bson:"history"`
-	InterestType        string          `json:"interestType,omitempty" bson:"interestType,omitempty"`
-	Frequency           string          `json:"frequency,omitempty" bson:"frequency,omitempty"`
-	ProjectedProfit     float64         `json:"projectedProfit,omitempty" bson:"projectedProfit,omitempty"`
-	AgreementDate       string          `json:"agreementDate,omitempty" bson:"agreementDate,omitempty"`
-	AgreementValue      float64         `json:"agreementValue,omitempty" bson:"agreementValue,omitempty"`
-	GuarantorName       string          `json:"guarantorName,omitempty" bson:"guarantorName,omitempty"`
-	GuarantorCPF        string          `json:"guarantorCPF,omitempty" bson:"guarantorCPF,omitempty"`
-	GuarantorAddress    string          `json:"guarantorAddress,omitempty" bson:"guarantorAddress,omitempty"`
-	AffiliateName       string          `json:"affiliateName,omitempty" bson:"affiliateName,omitempty"`
-	AffiliateFee        float64         `json:"affiliateFee,omitempty" bson:"affiliateFee,omitempty"`
-	AffiliateNotes      string          `json:"affiliateNotes,omitempty" bson:"affiliateNotes,omitempty"`
+	ID                    string               `json:"id" bson:"id"`
+	Client                string               `json:"client" bson:"client"`
+	Amount                money.Cents          `json:"amount" bson:"amount"`
+	Installments          int                  `json:"installments" bson:"installments"`
+	RemainingInstallments *int                 `json:"remainingInstallments,omitempty" bson:"remainingInstallments,omitempty"` // em aberto no cronograma; Installments é o prazo
+	InterestRate          float64              `json:"interestRate" bson:"interestRate"`
+	StartDate             string               `json:"startDate" bson:"startDate"`
+	NextDue               string               `json:"nextDue" bson:"nextDue"`
+	Status                string               `json:"status" bson:"status"`
+	InstallmentValue      money.Cents          `json:"installmentValue" bson:"installmentValue"`
+	FineRate              float64              `json:"fineRate" bson:"fineRate"`
+	MoraInterestRate      float64              `json:"moraInterestRate" bson:"moraInterestRate"`
+	ClientBank            string               `json:"clientBank" bson:"clientBank"`
+	PaymentMethod         string               `json:"paymentMethod" bson:"paymentMethod"`
+	Justification         string               `json:"justification,omitempty" bson:"justification,omitempty"`
+	ChecklistAtApproval   []string             `json:"checklistAtApproval,omitempty" bson:"checklistAtApproval,omitempty"`
+	TotalPaidInterest     money.Cents          `json:"totalPaidInterest" bson:"totalPaidInterest"`
+	TotalPaidCapital      money.Cents          `json:"totalPaidCapital" bson:"totalPaidCapital"`
+	TotalPaidCharges      money.Cents          `json:"totalPaidCharges,omitempty" bson:"totalPaidCharges,omitempty"`
+	History               []PaymentRecord      `json:"history" bson:"history"`
+	Schedule              []LoanInstallment    `json:"schedule,omitempty" bson:"schedule,omitempty"`
+	Agreements            []Agreement          `json:"agreements,omitempty" bson:"agreements,omitempty"`
+	InterestType          string               `json:"interestType,omitempty" bson:"interestType,omitempty"`
+	Frequency             string               `json:"frequency,omitempty" bson:"frequency,omitempty"`
+	DueDateRule           string               `json:"dueDateRule,omitempty" bson:"dueDateRule,omitempty"` // MANTER ou PROXIMO_UTIL
+	GraceDays             int                  `json:"graceDays,omitempty" bson:"graceDays,omitempty"`     // carência antes do primeiro vencimento
+	GraceRule             string               `json:"graceRule,omitempty" bson:"graceRule,omitempty"`     // CAPITALIZAR ou DIFERIR
+	GraceInterest         money.Cents          `json:"graceInterest,omitempty" bson:"graceInterest,omitempty"`
+	CorrectionIndex       string               `json:"correctionIndex,omitempty" bson:"correctionIndex,omitempty"` // IPCA, IGPM ou CDI sobre parcelas vencidas
+	ProjectedProfit       money.Cents          `json:"projectedProfit,omitempty" bson:"projectedProfit,omitempty"`
+	CET                   float64              `json:"cet,omitempty" bson:"cet,omitempty"` // custo efetivo total, % a.a.
+	IOFDaily              money.Cents          `json:"iofDaily,omitempty" bson:"iofDaily,omitempty"`
+	IOFAdditional         money.Cents          `json:"iofAdditional,omitempty" bson:"iofAdditional,omitempty"`
+	IOF                   money.Cents          `json:"iof,omitempty" bson:"iof,omitempty"` // IOF total na originação
+	AgreementDate         string               `json:"agreementDate,omitempty" bson:"agreementDate,omitempty"`
+	AgreementValue        money.Cents          `json:"agreementValue,omitempty" bson:"agreementValue,omitempty"`
+	GuarantorName         string               `json:"guarantorName,omitempty" bson:"guarantorName,omitempty"`
+	GuarantorCPF          string               `json:"guarantorCPF,omitempty" bson:"guarantorCPF,omitempty"`
+	GuarantorCPFDigits    string               `json:"guarantorCPFDigits,omitempty" bson:"guarantorCPFDigits,omitempty"`
+	GuarantorAddress      string               `json:"guarantorAddress,omitempty" bson:"guarantorAddress,omitempty"`
+	AffiliateID           string               `json:"affiliateId,omitempty" bson:"affiliateId,omitempty"`
+	AffiliateCode         string               `json:"affiliateCode,omitempty" bson:"affiliateCode,omitempty"`
+	AffiliateName         string               `json:"affiliateName,omitempty" bson:"affiliateName,omitempty"`
+	AffiliateCommission   *commission.Terms    `json:"affiliateCommission,omitempty" bson:"affiliateCommission,omitempty"` // condições vigentes na indicação
+	AffiliateFee          money.Cents          `json:"affiliateFee,omitempty" bson:"affiliateFee,omitempty"`               // comissão devida até agora; calculada quando vinculado
+	AffiliateNotes        string               `json:"affiliateNotes,omitempty" bson:"affiliateNotes,omitempty"`
+	RefinancedFrom        string               `json:"refinancedFrom,omitempty" bson:"refinancedFrom,omitempty"`           // contrato quitado por este
+	RefinancedBy          string               `json:"refinancedBy,omitempty" bson:"refinancedBy,omitempty"`               // contrato que quitou este
+	Product               string               `json:"product,omitempty" bson:"product,omitempty"`                         // produto comercial, usado na escolha do checklist
+	Checklist             *ChecklistSnapshot   `json:"checklist,omitempty" bson:"checklist,omitempty"`                     // versão do checklist conferida na aprovação
+	CreditLimitExceeded   bool                 `json:"creditLimitExceeded,omitempty" bson:"creditLimitExceeded,omitempty"` // gravado acima do limite do cliente
+	CreditLimitOverride   *CreditLimitOverride `json:"creditLimitOverride,omitempty" bson:"creditLimitOverride,omitempty"`
+	ApplicationID         string               `json:"applicationId,omitempty" bson:"applicationId,omitempty"` // proposta aprovada que gerou o contrato
+	WriteOff              *WriteOff            `json:"writeOff,omitempty" bson:"writeOff,omitempty"`           // baixa como perda
+	Recoveries            []Recovery           `json:"recoveries,omitempty" bson:"recoveries,omitempty"`       // recebido após a baixa
+	Version               int64                `json:"version" bson:"version"`
+	BlacklistHits         []BlacklistHit       `json:"blacklistHits,omitempty" bson:"blacklistHits,omitempty"` // alertas da lista negra na criação
 }
 
 type ClientDoc struct {
@@ -283,35 +434,40 @@ type ClientDoc struct {
 }
 
 type Client struct {
-	ID           int64       `json:"id" bson:"id"`
-	Name         string      `json:"name" bson:"name"`
-	CPF          string      `json:"cpf" bson:"cpf"`
-	RG           string      `json:"rg" bson:"rg"`
-	Email        string      `json:"email" bson:"email"`
-	Phone        string      `json:"phone" bson:"phone"`
-	Address      string      `json:"address" bson:"address"`
-	Number       string      `json:"number" bson:"number"`
-	Neighborhood string      `json:"neighborhood" bson:"neighborhood"`
-	City         string      `json:"city" bson:"city"`
-	State        string      `json:"state" bson:"state"`
-	CEP          string      `json:"cep" bson:"cep"`
-	Observations string      `json:"observations" bson:"observations"`
-	Documents    []ClientDoc `json:"documents" bson:"documents"`
-	Status       string      `json:"status" bson:"status"`
+	ID            int64          `json:"id" bson:"id"`
+	Name          string         `json:"name" bson:"name"`
+	CPF           string         `json:"cpf" bson:"cpf"`
+	CPFDigits     string         `json:"cpfDigits,omitempty" bson:"cpfDigits,omitempty"` // forma canônica, só dígitos
+	RG            string         `json:"rg" bson:"rg"`
+	Email         string         `json:"email" bson:"email"`
+	Phone         string         `json:"phone" bson:"phone"`
+	Address       string         `json:"address" bson:"address"`
+	Number        string         `json:"number" bson:"number"`
+	Neighborhood  string         `json:"neighborhood" bson:"neighborhood"`
+	City          string         `json:"city" bson:"city"`
+	State         string         `json:"state" bson:"state"`
+	CEP           string         `json:"cep" bson:"cep"`
+	Observations  string         `json:"observations" bson:"observations"`
+	Documents     []ClientDoc    `json:"documents" bson:"documents"`
+	Status        string         `json:"status" bson:"status"`
+	Score         *ClientScore   `json:"score,omitempty" bson:"score,omitempty"`                 // score interno, recalculado pela rotina diária
+	CreditLimit   money.Cents    `json:"creditLimit,omitempty" bson:"creditLimit,omitempty"`     // teto de capital em aberto no CPF; zero é sem limite
+	BlacklistHits []BlacklistHit `json:"blacklistHits,omitempty" bson:"blacklistHits,omitempty"` // alertas da lista negra no cadastro
 }
 
 type Affiliate struct {
-	ID              string  `json:"id" bson:"id"`
-	Name            string  `json:"name" bson:"name"`
-	Email           string  `json:"email" bson:"email"`
-	Phone           string  `json:"phone" bson:"phone"`
-	Code            string  `json:"code" bson:"code"`
-	Referrals       int     `json:"referrals" bson:"referrals"`
-	CommissionRate  float64 `json:"commissionRate" bson:"commissionRate"`
-	FixedCommission float64 `json:"fixedCommission" bson:"fixedCommission"`
-	Earned          float64 `json:"earned" bson:"earned"`
-	Status          string  `json:"status" bson:"status"`
-	PixKey          string  `json:"pixKey" bson:"pixKey"`
+	ID              string      `json:"id" bson:"id"`
+	Name            string      `json:"name" bson:"name"`
+	Email           string      `json:"email" bson:"email"`
+	Phone           string      `json:"phone" bson:"phone"`
+	Code            string      `json:"code" bson:"code"`
+	Referrals       int         `json:"referrals" bson:"-"`                   // derivado dos contratos vinculados
+	CommissionBase  string      `json:"commissionBase" bson:"commissionBase"` // ORIGINACAO ou JUROS
+	CommissionRate  float64     `json:"commissionRate" bson:"commissionRate"`
+	FixedCommission money.Cents `json:"fixedCommission" bson:"fixedCommission"`
+	Earned          money.Cents `json:"earned" bson:"-"` // derivado dos contratos vinculados
+	Status          string      `json:"status" bson:"status"`
+	PixKey          string      `json:"pixKey" bson:"pixKey"`
 }
 
 type LogEntry struct {
@@ -323,13 +479,14 @@ type LogEntry struct {
 }
 
 type BlacklistEntry struct {
-	ID     string `json:"id" bson:"id"`
-	Name   string `json:"name" bson:"name"`
-	CPF    string `json:"cpf" bson:"cpf"`
-	Reason string `json:"reason" bson:"reason"`
-	Date   string `json:"date" bson:"date"`
-	Risk   string `json:"riskLevel" bson:"riskLevel"`
-	Notes  string `json:"notes" bson:"notes"`
+	ID        string `json:"id" bson:"id"`
+	Name      string `json:"name" bson:"name"`
+	CPF       string `json:"cpf" bson:"cpf"`
+	CPFDigits string `json:"cpfDigits,omitempty" bson:"cpfDigits,omitempty"`
+	Reason    string `json:"reason" bson:"reason"`
+	Date      string `json:"date" bson:"date"`
+	Risk      string `json:"riskLevel" bson:"riskLevel"`
+	Notes     string `json:"notes" bson:"notes"`
 }
 
 type BackupData struct {
@@ -381,11 +538,22 @@ func main() {
 	logCollection = db.Collection("logs")
 	blacklistCollection = db.Collection("blacklist")
 	settingsCollection = db.Collection("settings")
+	migrationCollection = db.Collection("migrations")
+	indexRateCollection = db.Collection("index_rates")
+	applicationCollection = db.Collection("loan_applications")
 	log.Println("✅ MongoDB Conectado!")
 
 	seedAdminUser()
+	runMigrations()
+	ensureClientIndexes()
+	calCtx, calCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	reloadBusinessCalendar(calCtx)
+	reloadIndexTables(calCtx)
+	calCancel()
 	StartBackgroundSystemLogs()
 	StartDailyBackupRoutine()
+	StartLoanStatusRoutine()
+	StartClientScoreRoutine()
 
 	waSvc := NewWhatsappService()
 	waCtrl := NewWhatsappController(waSvc)
@@ -399,7 +567,10 @@ func main() {
 	mux.HandleFunc("/api/users", authMiddleware(usersHandler))
 	mux.HandleFunc("/api/users/", authMiddleware(userDetailHandler))
 	mux.HandleFunc("/api/loans", authMiddleware(loansHandler))
+	mux.HandleFunc("/api/loans/simulate", authMiddleware(loanSimulateHandler))
 	mux.HandleFunc("/api/loans/", authMiddleware(loanUpdateHandler))
+	mux.HandleFunc("/api/loan-applications", authMiddleware(applicationsHandler))
+	mux.HandleFunc("/api/loan-applications/", authMiddleware(applicationDetailHandler))
 	mux.HandleFunc("/api/clients", authMiddleware(clientsHandler))
 	mux.HandleFunc("/api/clients/", authMiddleware(clientUpdateHandler))
 	mux.HandleFunc("/api/affiliates", authMiddleware(affiliatesHandler))
@@ -408,10 +579,17 @@ func main() {
 	mux.HandleFunc("/api/blacklist/", authMiddleware(blacklistUpdateHandler))
 	mux.HandleFunc("/api/logs", authMiddleware(logsHandler))
 	mux.HandleFunc("/api/settings", authMiddleware(settingsHandler))
+	mux.HandleFunc("/api/settings/holidays", authMiddleware(holidaysHandler))
+	mux.HandleFunc("/api/settings/checklists", authMiddleware(checklistsHandler))
 	mux.HandleFunc("/api/dashboard/summary", authMiddleware(dashboardSummaryHandler))
+	mux.HandleFunc("/api/reports/agreements", authMiddleware(agreementsReportHandler))
+	mux.HandleFunc("/api/reports/iof", authMiddleware(iofReportHandler))
+	mux.HandleFunc("/api/reports/losses", authMiddleware(lossesReportHandler))
+	mux.HandleFunc("/api/indexes", authMiddleware(indexesHandler))
+	mux.HandleFunc("/api/indexes/", adminMiddleware(indexTableHandler))
 
 	// WhatsApp
-	mux.HandleFunc("/api/message", waCtrl.EnviarMensagem)
+	mux.HandleFunc("/api/message", authMiddleware(waCtrl.EnviarMensagem))
 	mux.HandleFunc("/api/instances/ver", waCtrl.VerInstancias)
 	mux.HandleFunc("/api/instances/criar", waCtrl.CriarInstanciaMsg)
 	mux.HandleFunc("/api/instances/conectar", waCtrl.ConectarInstancia)
@@ -613,9 +791,51 @@ func loansHandler(w http.ResponseWriter, r *http.Request) {
 		if results == nil { results = []Loan{} }
 		json.NewEncoder(w).Encode(results)
 	} else if r.Method == http.MethodPost {
+		if loadSettings(ctx).System.loanApprovalRequired() {
+			http.Error(w, errApprovalRequired.Error(), http.StatusForbidden)
+			return
+		}
 		var l Loan
 		json.NewDecoder(r.Body).Decode(&l)
+		if fe := l.normalizeDocuments(); len(fe) > 0 {
+			writeFieldErrors(w, http.StatusBadRequest, fe)
+			return
+		}
+		if fe, err := linkAffiliate(ctx, &l); err != nil {
+			http.Error(w, "Erro ao buscar afiliado", http.StatusInternalServerError)
+			return
+		} else if len(fe) > 0 {
+			writeFieldErrors(w, http.StatusBadRequest, fe)
+			return
+		}
+		// Sem proposta, o checklist marcado na tela (ChecklistAtApproval) é
+		// conferido aqui contra o modelo vigente; a cópia enviada é descartada.
+		if err := applyChecklist(selectChecklist(loadSettings(ctx).System.ChecklistTemplates, l), &l, l.ChecklistAtApproval, currentUser(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
 		l.ID = primitive.NewObjectID().Hex()
+		if err := screenLoanBlacklist(ctx, &l, "Novo contrato", currentUser(r)); err != nil {
+			if blocked, ok := err.(blacklistBlockedError); ok {
+				writeFieldErrors(w, http.StatusUnprocessableEntity, blocked.fields())
+			} else {
+				http.Error(w, "Erro ao consultar a lista negra", http.StatusInternalServerError)
+			}
+			return
+		}
+		if err := prepareNewLoan(ctx, &l); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := checkCreditLimit(ctx, &l, currentUser(r)); err != nil {
+			if _, ok := err.(creditLimitError); ok {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			} else {
+				http.Error(w, "Erro ao conferir limite de crédito", http.StatusInternalServerError)
+			}
+			return
+		}
+		l.refreshAffiliateFee()
 		loanCollection.InsertOne(ctx, l)
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(l)
@@ -623,13 +843,51 @@ func loansHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func loanUpdateHandler(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/api/loans/")
+	id, action := splitLoanPath(r.URL.Path)
+	if len(action) > 0 {
+		loanActionHandler(w, r, id, action)
+		return
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if r.Method == http.MethodPut {
-		var l Loan
-		json.NewDecoder(r.Body).Decode(&l)
-		loanCollection.ReplaceOne(ctx, bson.M{"id": id}, l)
+		var req Loan
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		if fe := req.normalizeDocuments(); len(fe) > 0 {
+			writeFieldErrors(w, http.StatusBadRequest, fe)
+			return
+		}
+		// Só os dados cadastrais vêm do front; o resto é mantido do documento gravado.
+		l, err := editLoan(ctx, id, req, currentUser(r))
+		if _, ok := err.(invalidTermsError); ok {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, ok := err.(creditLimitError); ok {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		switch err {
+		case nil:
+		case errLoanNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errLoanConflict:
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case errInvalidIndex, errInvalidPromiseDue:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errLoanTermsLocked, errLoanWrittenOff:
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		json.NewEncoder(w).Encode(l)
 	} else if r.Method == http.MethodDelete {
 		loanCollection.DeleteOne(ctx, bson.M{"id": id})
@@ -650,8 +908,31 @@ func clientsHandler(w http.ResponseWriter, r *http.Request) {
 	} else if r.Method == http.MethodPost {
 		var c Client
 		json.NewDecoder(r.Body).Decode(&c)
+		if fe := c.normalizeDocuments(); len(fe) > 0 {
+			writeFieldErrors(w, http.StatusBadRequest, fe)
+			return
+		}
 		if c.ID == 0 { c.ID = time.Now().UnixNano() / 1e6 }
-		clientCollection.InsertOne(ctx, c)
+		hits, err := screenBlacklist(ctx, "Cadastro do cliente "+c.Name, currentUser(r), screenedDoc{"Cliente", "cpf", c.CPF})
+		if blocked, ok := err.(blacklistBlockedError); ok {
+			writeFieldErrors(w, http.StatusUnprocessableEntity, blocked.fields())
+			return
+		} else if err != nil {
+			http.Error(w, "Erro ao consultar a lista negra", http.StatusInternalServerError)
+			return
+		}
+		c.BlacklistHits = hits
+		c.Score = nil // calculado pela rotina diária
+		if !authorizeCreditLimitChange(ctx, w, currentUser(r), c, 0) {
+			return
+		}
+		if fe, err := saveClient(ctx, c, true); err != nil {
+			http.Error(w, "Erro ao salvar cliente", http.StatusInternalServerError)
+			return
+		} else if len(fe) > 0 {
+			writeFieldErrors(w, http.StatusConflict, fe)
+			return
+		}
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(c)
 	}
@@ -659,13 +940,57 @@ func clientsHandler(w http.ResponseWriter, r *http.Request) {
 
 func clientUpdateHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/clients/")
+	idStr, action, _ := strings.Cut(idStr, "/")
+	if idStr == "duplicates" {
+		clientDuplicatesHandler(w, r)
+		return
+	}
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	switch action {
+	case "":
+	case "merge":
+		clientMergeHandler(w, r, id)
+		return
+	case "exposure":
+		clientExposureHandler(w, r, id)
+		return
+	case "score":
+		clientScoreHandler(w, r, id)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	id, _ := strconv.ParseInt(idStr, 10, 64)
 	if r.Method == http.MethodPut {
 		var c Client
 		json.NewDecoder(r.Body).Decode(&c)
-		clientCollection.ReplaceOne(ctx, bson.M{"id": id}, c)
+		if fe := c.normalizeDocuments(); len(fe) > 0 {
+			writeFieldErrors(w, http.StatusBadRequest, fe)
+			return
+		}
+		c.ID = id
+		current, err := findClientByID(ctx, id)
+		if err != nil && err != mongo.ErrNoDocuments {
+			http.Error(w, "Erro ao buscar cliente", http.StatusInternalServerError)
+			return
+		}
+		if c.BlacklistHits == nil {
+			c.BlacklistHits = current.BlacklistHits
+		}
+		// O score é do servidor (rotina diária); o cadastro não o altera.
+		c.Score = current.Score
+		if !authorizeCreditLimitChange(ctx, w, currentUser(r), c, current.CreditLimit) {
+			return
+		}
+		if fe, err := saveClient(ctx, c, false); err != nil {
+			http.Error(w, "Erro ao salvar cliente", http.StatusInternalServerError)
+			return
+		} else if len(fe) > 0 {
+			writeFieldErrors(w, http.StatusConflict, fe)
+			return
+		}
 		json.NewEncoder(w).Encode(c)
 	} else if r.Method == http.MethodDelete {
 		clientCollection.DeleteOne(ctx, bson.M{"id": id})
@@ -675,46 +1000,6 @@ func clientUpdateHandler(w http.ResponseWriter, r *http.Request) {
 
 // --- Restante das Funções Auxiliares (WhatsApp, Settings, Dashboard) ---
 
-func affiliatesHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	cursor, _ := affiliateCollection.Find(ctx, bson.M{})
-	var res []Affiliate
-	cursor.All(ctx, &res)
-	if res == nil { res = []Affiliate{} }
-	json.NewEncoder(w).Encode(res)
-}
-
-func affiliateUpdateHandler(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/api/affiliates/")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if r.Method == http.MethodDelete {
-		affiliateCollection.DeleteOne(ctx, bson.M{"id": id})
-		w.WriteHeader(http.StatusNoContent)
-	}
-}
-
-func blacklistHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	cursor, _ := blacklistCollection.Find(ctx, bson.M{})
-	var res []BlacklistEntry
-	cursor.All(ctx, &res)
-	if res == nil { res = []BlacklistEntry{} }
-	json.NewEncoder(w).Encode(res)
-}
-
-func blacklistUpdateHandler(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/api/blacklist/")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if r.Method == http.MethodDelete {
-		blacklistCollection.DeleteOne(ctx, bson.M{"id": id})
-		w.WriteHeader(http.StatusNoContent)
-	}
-}
-
 func settingsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -725,8 +1010,54 @@ func settingsHandler(w http.ResponseWriter, r *http.Request) {
 	} else {
 		var s Settings
 		json.NewDecoder(r.Body).Decode(&s)
+		if fe := s.Company.normalizeDocuments(); len(fe) > 0 {
+			writeFieldErrors(w, http.StatusBadRequest, fe)
+			return
+		}
+		// A tela de configurações não envia os campos mantidos por rotas próprias
+		current := loadSettings(ctx)
+		// Exigência de proposta e modo do limite de crédito só mudam por ADMIN
+		var adminChanges []string
+		if s.System.RequireLoanApproval == nil {
+			s.System.RequireLoanApproval = current.System.RequireLoanApproval
+		} else if *s.System.RequireLoanApproval != current.System.loanApprovalRequired() {
+			adminChanges = append(adminChanges, fmt.Sprintf("Exigência de proposta aprovada: %t", *s.System.RequireLoanApproval))
+		}
+		s.System.CreditLimitMode = strings.ToUpper(strings.TrimSpace(s.System.CreditLimitMode))
+		switch s.System.CreditLimitMode {
+		case "":
+			s.System.CreditLimitMode = current.System.CreditLimitMode
+		case CreditLimitBlock, CreditLimitFlag:
+			if s.System.creditLimitMode() != current.System.creditLimitMode() {
+				adminChanges = append(adminChanges, "Limite de crédito: "+s.System.CreditLimitMode)
+			}
+		default:
+			http.Error(w, "Modo do limite de crédito inválido: use BLOQUEAR ou SINALIZAR", http.StatusBadRequest)
+			return
+		}
+		if len(adminChanges) > 0 {
+			user := currentUser(r)
+			if !isAdminUser(ctx, user) {
+				logAction("ACESSO NEGADO ADMIN", user)
+				http.Error(w, "Acesso restrito a Administradores.", http.StatusForbidden)
+				return
+			}
+			logUserAction("CONFIGURAÇÕES", user, strings.Join(adminChanges, "; "))
+		}
+		// Feriados locais só mudam por /api/settings/holidays (ADMIN)
+		s.System.LocalHolidays = current.System.LocalHolidays
+		// Checklists só mudam por /api/settings/checklists (ADMIN, com versão)
+		s.System.ChecklistTemplates = current.System.ChecklistTemplates
+		if s.System.PaymentAllocationOrder == nil {
+			s.System.PaymentAllocationOrder = current.System.PaymentAllocationOrder
+		}
+		if s.System.IOFDailyRate == 0 && s.System.IOFAdditionalRate == 0 {
+			s.System.IOFDailyRate = current.System.IOFDailyRate
+			s.System.IOFAdditionalRate = current.System.IOFAdditionalRate
+		}
 		opts := options.Replace().SetUpsert(true)
 		settingsCollection.ReplaceOne(ctx, bson.M{}, s, opts)
+		reloadBusinessCalendar(ctx)
 		json.NewEncoder(w).Encode(s)
 	}
 }
@@ -744,10 +1075,12 @@ func logsHandler(w http.ResponseWriter, r *http.Request) {
 func dashboardSummaryHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	totalActive, _ := loanCollection.CountDocuments(ctx, bson.M{"status": bson.M{"$ne": "Pago"}})
+	totalActive, _ := loanCollection.CountDocuments(ctx, bson.M{"status": bson.M{"$nin": closedLoanStatuses}})
+	totalOverdue, _ := loanCollection.CountDocuments(ctx, bson.M{"status": "Atrasado"})
 	totalClients, _ := clientCollection.CountDocuments(ctx, bson.M{})
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"totalActive":       totalActive,
+		"totalOverdue":      totalOverdue,
 		"clientsRegistered": totalClients,
 	})
 }
@@ -787,8 +1120,26 @@ func (ctrl *WhatsappController) EnviarMensagem(w http.ResponseWriter, r *http.Re
 		UpdatedAmount float64 `json:"updatedAmount"`
 		DateVencimento string `json:"dateVencimento"`
 		ApiKey string `json:"apiKey" binding:"required"`
+		LoanID string `json:"loanId"`
 	}
 	json.NewDecoder(r.Body).Decode(&body)
+	// Com o contrato informado, valor e atraso vêm do servidor (mesmo cálculo do /payoff)
+	if body.LoanID != "" {
+		l, err := findLoan(r.Context(), body.LoanID)
+		if err == errLoanNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Erro ao buscar contrato", http.StatusInternalServerError)
+			return
+		}
+		amount, days, due, err := reminderValues(l)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		body.UpdatedAmount, body.LateDays, body.DateVencimento = amount.Float(), days, due
+	}
 	err := ctrl.svc.SendMessage(r.Context(), body.UserConectado, body.Phone, body.Message, body.Delay, body.Name, body.LateDays, body.UpdatedAmount, body.DateVencimento, body.ApiKey)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// --- Migrações de Dados ---
+
+// migrationCollection guarda as migrações já aplicadas ({_id, appliedAt, details}).
+var migrationCollection *mongo.Collection
+
+type migration struct {
+	ID  string
+	Run func(ctx context.Context) (string, error)
+}
+
+// migrations roda em ordem na subida do servidor; cada uma só uma vez.
+var migrations = []migration{
+	{ID: "2026-10-money-centavos", Run: migrateMoneyToCents},
+	{ID: "2026-10-documentos-cpf-cnpj", Run: migrateDocuments},
+	{ID: "2026-10-afiliados-vinculados", Run: migrateAffiliateLinks},
+	{ID: "2026-10-prazo-parcelas", Run: migrateRemainingInstallments},
+}
+
+func runMigrations() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	for _, m := range migrations {
+		if n, _ := migrationCollection.CountDocuments(ctx, bson.M{"_id": m.ID}); n > 0 {
+			continue
+		}
+		log.Printf("🔄 Migração %s...", m.ID)
+		details, err := m.Run(ctx)
+		if err != nil {
+			log.Printf("❌ Migração %s falhou: %v", m.ID, err)
+			return
+		}
+		migrationCollection.InsertOne(ctx, bson.M{"_id": m.ID, "appliedAt": time.Now(), "details": details})
+		logSysAction("MIGRAÇÃO", m.ID+": "+details)
+	}
+}
+
+// legacyMoneyFilter encontra documentos com algum valor monetário ainda gravado
+// como double em reais.
+func legacyMoneyFilter(fields ...string) bson.M {
+	or := make([]bson.M, len(fields))
+	for i, f := range fields {
+		or[i] = bson.M{f: bson.M{"$type": "double"}}
+	}
+	return bson.M{"$or": or}
+}
+
+// migrateMoneyToCents regrava contratos e afiliados com os valores em centavos
+// inteiros. A leitura já converte double em reais (money.Cents), então basta
+// decodificar e gravar de novo.
+func migrateMoneyToCents(ctx context.Context) (string, error) {
+	loans, err := rewriteDocuments(ctx, loanCollection, legacyMoneyFilter(
+		"amount", "installmentValue", "totalPaidInterest", "totalPaidCapital", "totalPaidCharges",
+		"projectedProfit", "agreementValue", "affiliateFee",
+		"history.amount", "history.capitalPaid", "history.interestPaid",
+		"schedule.expectedCapital", "schedule.paidCapital", "agreements.total",
+	), func() interface{} { return &Loan{} }, func(doc interface{}) string { return doc.(*Loan).ID })
+	if err != nil {
+		return "", err
+	}
+	affiliates, err := rewriteDocuments(ctx, affiliateCollection, legacyMoneyFilter("fixedCommission", "earned"),
+		func() interface{} { return &Affiliate{} }, func(doc interface{}) string { return doc.(*Affiliate).ID })
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d contrato(s) e %d afiliado(s) convertidos para centavos", loans, affiliates), nil
+}
+
+func rewriteDocuments(ctx context.Context, coll *mongo.Collection, filter bson.M, newDoc func() interface{}, id func(interface{}) string) (int, error) {
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+	n := 0
+	for cursor.Next(ctx) {
+		doc := newDoc()
+		if err := cursor.Decode(doc); err != nil {
+			return n, err
+		}
+		if _, err := coll.ReplaceOne(ctx, bson.M{"_id": cursor.Current.Lookup("_id")}, doc); err != nil {
+			return n, fmt.Errorf("%s: %w", id(doc), err)
+		}
+		n++
+	}
+	return n, cursor.Err()
+}
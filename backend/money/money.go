@@ -0,0 +1,100 @@
+// Package money representa valores em reais como inteiros de centavos, sem a
+// deriva de arredondamento do float64.
+//
+// No JSON o valor continua sendo um número em reais com duas casas (1234.56),
+// como o front espera. No MongoDB é gravado como int64 de centavos; documentos
+// antigos, gravados como double em reais, são lidos e convertidos na leitura.
+package money
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// Cents é um valor monetário em centavos de real.
+type Cents int64
+
+// FromFloat converte reais para centavos, arredondando meio centavo para longe do zero.
+func FromFloat(reais float64) Cents {
+	return Cents(math.Round(reais * 100))
+}
+
+// Float devolve o valor em reais, para cálculos com taxas e para o motor financeiro.
+func (c Cents) Float() float64 {
+	return float64(c) / 100
+}
+
+// Mul multiplica por um fator (ex: taxa) e arredonda para o centavo.
+func (c Cents) Mul(f float64) Cents {
+	return FromFloat(c.Float() * f)
+}
+
+// Percent devolve p% do valor, arredondado para o centavo.
+func (c Cents) Percent(p float64) Cents {
+	return c.Mul(p / 100)
+}
+
+// Div divide em n partes, arredondando para o centavo.
+func (c Cents) Div(n int) Cents {
+	return Cents(math.Round(float64(c) / float64(n)))
+}
+
+// String formata como decimal com duas casas ("1234.56"), usado em logs e no JSON.
+func (c Cents) String() string {
+	sign := ""
+	v := int64(c)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// MarshalJSON grava o valor em reais como número.
+func (c Cents) MarshalJSON() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalJSON aceita número ou string em reais ("1234.56"); null vira zero.
+func (c *Cents) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	if len(data) == 0 || string(data) == "null" {
+		*c = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("valor monetário inválido: %s", data)
+	}
+	*c = FromFloat(f)
+	return nil
+}
+
+// MarshalBSONValue grava centavos como int64.
+func (c Cents) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bsontype.Int64, bsoncore.AppendInt64(nil, int64(c)), nil
+}
+
+// UnmarshalBSONValue lê int64/int32 como centavos e double como reais (formato
+// anterior à migração).
+func (c *Cents) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	v := bsoncore.Value{Type: t, Data: data}
+	switch t {
+	case bsontype.Int64:
+		*c = Cents(v.Int64())
+	case bsontype.Int32:
+		*c = Cents(v.Int32())
+	case bsontype.Double:
+		*c = FromFloat(v.Double())
+	case bsontype.Null, bsontype.Undefined:
+		*c = 0
+	default:
+		return fmt.Errorf("valor monetário com tipo BSON inesperado: %s", t)
+	}
+	return nil
+}
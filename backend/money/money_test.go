@@ -0,0 +1,95 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFromFloat(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want Cents
+	}{
+		{0.1 + 0.2, 30},
+		{1234.565, 123457},
+		{-10.005, -1001},
+		{888.49, 88849},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := FromFloat(tt.in); got != tt.want {
+			t.Errorf("FromFloat(%v) = %d, esperado %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type doc struct {
+		Amount Cents `json:"amount"`
+		Fee    Cents `json:"fee,omitempty"`
+	}
+	out, err := json.Marshal(doc{Amount: 123405})
+	if err != nil || string(out) != `{"amount":1234.05}` {
+		t.Fatalf("Marshal = %s, %v", out, err)
+	}
+	tests := map[string]Cents{
+		`{"amount":1234.05}`:            123405,
+		`{"amount":"99.9"}`:             9990,
+		`{"amount":300.00000000000006}`: 30000,
+		`{"amount":null}`:               0,
+		`{"amount":-0.01}`:              -1,
+	}
+	for in, want := range tests {
+		var d doc
+		if err := json.Unmarshal([]byte(in), &d); err != nil || d.Amount != want {
+			t.Errorf("Unmarshal(%s) = %d, %v; esperado %d", in, d.Amount, err, want)
+		}
+	}
+	var d doc
+	if err := json.Unmarshal([]byte(`{"amount":"abc"}`), &d); err == nil {
+		t.Error("esperado erro para valor não numérico")
+	}
+}
+
+func TestBSON(t *testing.T) {
+	type doc struct {
+		Amount Cents `bson:"amount"`
+	}
+	raw, err := bson.Marshal(doc{Amount: 88849})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m bson.M
+	bson.Unmarshal(raw, &m)
+	if v, ok := m["amount"].(int64); !ok || v != 88849 {
+		t.Fatalf("gravado como %T %v, esperado int64 88849", m["amount"], m["amount"])
+	}
+
+	// documento antigo em reais (double)
+	legacy, _ := bson.Marshal(bson.M{"amount": 888.49})
+	var d doc
+	if err := bson.Unmarshal(legacy, &d); err != nil || d.Amount != 88849 {
+		t.Fatalf("leitura de double = %d, %v", d.Amount, err)
+	}
+	legacy, _ = bson.Marshal(bson.M{"amount": int32(150)})
+	if err := bson.Unmarshal(legacy, &d); err != nil || d.Amount != 150 {
+		t.Fatalf("leitura de int32 = %d, %v", d.Amount, err)
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := map[Cents]string{0: "0.00", 5: "0.05", 123456: "1234.56", -250: "-2.50"}
+	for in, want := range tests {
+		if got := in.String(); got != want {
+			t.Errorf("%d.String() = %s, esperado %s", in, got, want)
+		}
+	}
+	if got := Cents(10000).Div(3); got != 3333 {
+		t.Errorf("Div = %d, esperado 3333", got)
+	}
+	if got := Cents(10000).Percent(1.5); got != 150 {
+		t.Errorf("Percent = %d, esperado 150", got)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jules-playground/lms-backend/finance"
+	"github.com/jules-playground/lms-backend/money"
+)
+
+// --- Pausa de Pagamento (carência no meio do contrato) ---
+
+// maxHolidayPeriods limita quantos períodos uma única pausa pode adiar.
+const maxHolidayPeriods = 12
+
+var (
+	errInvalidPaymentHoliday = errors.New("Informe de 1 a 12 períodos de pausa")
+	errHolidayOverdue        = errors.New("Contrato possui parcelas em atraso; regularize ou faça um acordo antes da pausa")
+	errNothingToPause        = errors.New("Contrato não possui parcelas a vencer")
+)
+
+type paymentHolidayRequest struct {
+	Periods   int    `json:"periods"` // períodos da periodicidade do contrato (padrão 1)
+	Rule      string `json:"rule"`    // CAPITALIZAR ou DIFERIR (padrão: regra de carência do contrato)
+	Reason    string `json:"reason"`
+	RequestID string `json:"requestId"`
+}
+
+// periodDays devolve a duração comercial de um período, a mesma usada nas taxas.
+func periodDays(frequency string) int {
+	switch finance.NormalizeFrequency(frequency) {
+	case finance.FreqDaily:
+		return 1
+	case finance.FreqWeekly:
+		return 7
+	default:
+		return 30
+	}
+}
+
+// shiftDueDate adia o vencimento em n períodos da periodicidade.
+func shiftDueDate(due time.Time, frequency string, n int) time.Time {
+	return finance.DueDate(due, frequency, n+1)
+}
+
+// applyPaymentHoliday adia as parcelas a vencer do contrato em req.Periods
+// períodos. Os juros do período de pausa incidem sobre o capital em aberto dessas
+// parcelas e entram nelas pela regra de carência (ver finance.SpreadGraceInterest).
+// Devolve os juros acrescidos e os vencimentos antes/depois da primeira parcela adiada.
+func applyPaymentHoliday(l *Loan, req paymentHolidayRequest, ref time.Time) (money.Cents, string, string, error) {
+	var targets []int
+	for i, in := range l.Schedule {
+		switch in.Status {
+		case InstallmentOverdue:
+			return 0, "", "", errHolidayOverdue
+		case InstallmentPending, InstallmentPartial:
+			targets = append(targets, i)
+		}
+	}
+	if len(targets) == 0 {
+		return 0, "", "", errNothingToPause
+	}
+
+	var capital money.Cents
+	for _, i := range targets {
+		capital += l.Schedule[i].ExpectedCapital - l.Schedule[i].PaidCapital
+	}
+	interest := finance.GraceInterest(capital.Float(), l.InterestRate, req.Periods*periodDays(l.Frequency))
+	extras := finance.SpreadGraceInterest(interest, finance.PeriodicRate(l.InterestRate, l.Frequency), len(targets), req.Rule)
+
+	adjust := dueDateAdjuster(l.DueDateRule)
+	before := l.Schedule[targets[0]].DueDate
+	var prev time.Time
+	var added money.Cents
+	for k, i := range targets {
+		in := &l.Schedule[i]
+		due, err := parseLoanDate(in.DueDate)
+		if err != nil {
+			return 0, "", "", err
+		}
+		d := shiftDueDate(due, l.Frequency, req.Periods)
+		if adjust != nil {
+			d = adjust(d)
+		}
+		// Na periodicidade diária o ajuste de dia útil pode juntar duas parcelas.
+		if !prev.IsZero() && !d.After(prev) {
+			d = prev.AddDate(0, 0, 1)
+			if adjust != nil {
+				d = adjust(d)
+			}
+		}
+		prev = d
+		in.DueDate = d.Format(finance.DateLayout)
+		extra := money.FromFloat(extras[k])
+		in.ExpectedInterest += extra
+		added += extra
+	}
+	l.ProjectedProfit += added
+	refreshLoanState(l, ref)
+	return added, before, l.Schedule[targets[0]].DueDate, nil
+}
+
+// pauseLoan registra a pausa no contrato com controle de concorrência otimista.
+// Um requestId repetido devolve o contrato sem adiar de novo.
+func pauseLoan(ctx context.Context, id string, req paymentHolidayRequest, user string) (Loan, bool, error) {
+	if req.Periods == 0 {
+		req.Periods = 1
+	}
+	if req.Periods < 0 || req.Periods > maxHolidayPeriods {
+		return Loan{}, false, errInvalidPaymentHoliday
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		l, err := findLoan(ctx, id)
+		if err != nil {
+			return l, false, err
+		}
+		if req.RequestID != "" {
+			for _, h := range l.History {
+				if h.RequestID == req.RequestID {
+					return l, false, nil
+				}
+			}
+		}
+		rule := req.Rule
+		if rule == "" {
+			rule = l.GraceRule
+		}
+		if req.Rule, err = finance.NormalizeGraceRule(rule); err != nil {
+			return l, false, err
+		}
+		if l.WriteOff != nil {
+			return l, false, errLoanWrittenOff
+		}
+		if err := ensureLoanSchedule(&l); err != nil {
+			return l, false, err
+		}
+		ref := today()
+		applyPaymentsToSchedule(&l, ref)
+		interest, before, after, err := applyPaymentHoliday(&l, req, ref)
+		if err != nil {
+			return l, false, err
+		}
+
+		note := fmt.Sprintf("PAUSA: %d período(s); vencimento de %s adiado para %s e seguintes na mesma proporção. Juros do período: R$ %s (%s).",
+			req.Periods, before, after, interest, req.Rule)
+		if req.Reason != "" {
+			note += " " + req.Reason
+		}
+		l.History = append(l.History, PaymentRecord{
+			Date:            ref.Format(finance.DateLayout),
+			Type:            "Pausa",
+			Note:            note,
+			RegisteredAt:    time.Now().Format(time.RFC3339),
+			OriginalDueDate: before,
+			RequestID:       req.RequestID,
+			User:            user,
+			Reason:          req.Reason,
+		})
+
+		err = saveLoanVersioned(ctx, &l)
+		if err == errLoanConflict {
+			continue
+		}
+		if err != nil {
+			return l, false, err
+		}
+		logUserAction("PAUSA DE PAGAMENTO", user, fmt.Sprintf("Contrato %s (%s): %d período(s), vencimento de %s para %s, juros R$ %s (%s)",
+			l.ID, l.Client, req.Periods, before, after, interest, req.Rule))
+		return l, true, nil
+	}
+	return Loan{}, false, errLoanConflict
+}
+
+func loanPaymentHolidayHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req paymentHolidayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	l, created, err := pauseLoan(ctx, id, req, currentUser(r))
+	switch err {
+	case nil:
+	case errLoanNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errInvalidPaymentHoliday, finance.ErrInvalidGraceRule:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errHolidayOverdue, errNothingToPause, errLoanWrittenOff:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case errLoanConflict:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(l)
+}
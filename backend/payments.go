@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jules-playground/lms-backend/finance"
+	"github.com/jules-playground/lms-backend/money"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// --- Registro de Pagamentos ---
+
+// Componentes de abatimento de um pagamento.
+const (
+	AllocFine       = "fine"
+	AllocMora       = "mora"
+	AllocCorrection = "correction"
+	AllocInterest   = "interest"
+	AllocCapital    = "capital"
+)
+
+var defaultAllocationOrder = []string{AllocFine, AllocMora, AllocCorrection, AllocInterest, AllocCapital}
+
+var (
+	errInvalidAmount         = errors.New("Valor do pagamento deve ser maior que zero")
+	errInvalidPaymentDate    = errors.New("Data do pagamento inválida")
+	errInvalidAllocation     = errors.New("Ordem de abatimento inválida")
+	errPaymentExceedsBalance = errors.New("Valor excede o saldo devedor do contrato")
+	errLoanConflict          = errors.New("Contrato alterado por outra operação, tente novamente")
+	errPaymentNotFound       = errors.New("Lançamento não encontrado")
+	errNotReversible         = errors.New("Lançamento não pode ser estornado")
+	errAlreadyReversed       = errors.New("Lançamento já estornado")
+	errRefinancedReversal    = errors.New("Contrato quitado por refinanciamento: lançamentos não podem ser estornados enquanto o contrato novo existir")
+	errMissingJustification  = errors.New("Justificativa obrigatória")
+)
+
+type paymentRequest struct {
+	Amount    money.Cents `json:"amount"`
+	Date      string      `json:"date"`
+	Note      string      `json:"note"`
+	Order     []string    `json:"order"`
+	RequestID string      `json:"requestId"`
+}
+
+// normalizeAllocationOrder valida a ordem informada e completa os componentes
+// ausentes na ordem padrão.
+func normalizeAllocationOrder(order []string) ([]string, error) {
+	seen := map[string]bool{}
+	var out []string
+	for _, o := range order {
+		switch o {
+		case AllocFine, AllocMora, AllocCorrection, AllocInterest, AllocCapital:
+		default:
+			return nil, errInvalidAllocation
+		}
+		if seen[o] {
+			return nil, errInvalidAllocation
+		}
+		seen[o] = true
+		out = append(out, o)
+	}
+	for _, o := range defaultAllocationOrder {
+		if !seen[o] {
+			out = append(out, o)
+		}
+	}
+	return out, nil
+}
+
+// installmentCharges devolve multa e mora ainda devidas da parcela na data de referência.
+func installmentCharges(l Loan, in LoanInstallment, ref time.Time) (money.Cents, money.Cents) {
+	expected := in.ExpectedCapital + in.ExpectedInterest
+	open := expected - in.PaidCapital - in.PaidInterest
+	fine, mora := finance.LateCharge(expected.Float(), open.Float(), l.FineRate, l.MoraInterestRate, chargeableDaysLate(in.DueDate, ref))
+	return max(0, money.FromFloat(fine)-in.PaidFine), max(0, money.FromFloat(mora)-in.PaidMora)
+}
+
+// allocatePayment distribui o valor pelas parcelas em aberto (acordos ativos
+// primeiro, depois o contrato, cada um da mais antiga para a mais nova), seguindo
+// a ordem de componentes. Devolve as alocações e a sobra.
+func allocatePayment(l Loan, amount money.Cents, ref time.Time, order []string) ([]PaymentAllocation, money.Cents) {
+	var allocations []PaymentAllocation
+	left := amount
+	for _, in := range openInstallments(l) {
+		if left <= 0 {
+			break
+		}
+		fine, mora := installmentCharges(l, in.LoanInstallment, ref)
+		due := map[string]money.Cents{
+			AllocFine:       fine,
+			AllocMora:       mora,
+			AllocCorrection: installmentCorrection(l, in, ref),
+			AllocInterest:   in.ExpectedInterest - in.PaidInterest,
+			AllocCapital:    in.ExpectedCapital - in.PaidCapital,
+		}
+		a := PaymentAllocation{Agreement: in.Agreement, Installment: in.Number}
+		for _, component := range order {
+			part := min(left, max(0, due[component]))
+			left -= part
+			switch component {
+			case AllocFine:
+				a.Fine = part
+			case AllocMora:
+				a.Mora = part
+			case AllocCorrection:
+				a.Correction = part
+			case AllocInterest:
+				a.Interest = part
+			case AllocCapital:
+				a.Capital = part
+			}
+		}
+		if a.Fine+a.Mora+a.Correction+a.Interest+a.Capital > 0 {
+			allocations = append(allocations, a)
+		}
+	}
+	return allocations, left
+}
+
+// saveLoanVersioned grava o contrato só se ninguém o alterou desde a leitura.
+func saveLoanVersioned(ctx context.Context, l *Loan) error {
+	filter := bson.M{"id": l.ID, "version": l.Version}
+	if l.Version == 0 {
+		filter = bson.M{"id": l.ID, "$or": []bson.M{{"version": 0}, {"version": bson.M{"$exists": false}}}}
+	}
+	l.Version++
+	l.refreshAffiliateFee()
+	res, err := loanCollection.ReplaceOne(ctx, filter, l)
+	if err == nil && res.MatchedCount == 0 {
+		err = errLoanConflict
+	}
+	if err != nil {
+		l.Version--
+	}
+	return err
+}
+
+func paymentRecordType(capital, interest money.Cents) string {
+	switch {
+	case capital > 0 && interest > 0:
+		return "Parcela"
+	case capital > 0:
+		return "Amortização"
+	default:
+		return "Juros"
+	}
+}
+
+// newPaymentRecord monta o lançamento a partir das alocações, somando os componentes.
+func newPaymentRecord(l Loan, allocations []PaymentAllocation, payDate time.Time, user string) PaymentRecord {
+	rec := PaymentRecord{
+		Date:            payDate.Format(finance.DateLayout),
+		RegisteredAt:    time.Now().Format(time.RFC3339),
+		OriginalDueDate: l.NextDue,
+		Allocations:     allocations,
+		User:            user,
+	}
+	for _, a := range allocations {
+		rec.FinePaid += a.Fine
+		rec.MoraPaid += a.Mora
+		rec.CorrectionPaid += a.Correction
+		rec.InterestPaid += a.Interest
+		rec.CapitalPaid += a.Capital
+	}
+	rec.Amount = rec.FinePaid + rec.MoraPaid + rec.CorrectionPaid + rec.InterestPaid + rec.CapitalPaid
+	return rec
+}
+
+// appendPaymentRecord anexa o lançamento ao histórico e atualiza os totais pagos.
+func appendPaymentRecord(l *Loan, rec PaymentRecord) {
+	l.History = append(l.History, rec)
+	l.TotalPaidCapital += rec.CapitalPaid
+	l.TotalPaidInterest += rec.InterestPaid
+	l.TotalPaidCharges += rec.FinePaid + rec.MoraPaid + rec.CorrectionPaid
+}
+
+// registerPayment aplica o pagamento no contrato com controle de concorrência otimista.
+// Um requestId repetido devolve o registro existente em vez de baixar de novo.
+func registerPayment(ctx context.Context, id string, req paymentRequest, order []string, user string) (Loan, bool, error) {
+	if req.Amount <= 0 {
+		return Loan{}, false, errInvalidAmount
+	}
+	payDate := today()
+	if req.Date != "" {
+		d, err := parseLoanDate(req.Date)
+		if err != nil {
+			return Loan{}, false, errInvalidPaymentDate
+		}
+		payDate = d
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		l, err := findLoan(ctx, id)
+		if err != nil {
+			return l, false, err
+		}
+		if req.RequestID != "" {
+			for _, h := range l.History {
+				if h.RequestID == req.RequestID {
+					return l, false, nil
+				}
+			}
+		}
+		if l.WriteOff != nil {
+			return l, false, errLoanWrittenOff
+		}
+		if err := ensureLoanSchedule(&l); err != nil {
+			return l, false, err
+		}
+		applyPaymentsToSchedule(&l, payDate)
+
+		allocations, left := allocatePayment(l, req.Amount, payDate, order)
+		if left > paidTolerance || (left > 0 && len(allocations) == 0) {
+			return l, false, errPaymentExceedsBalance
+		}
+		// Sobra de arredondamento vai para o capital da última parcela, para o
+		// lançamento somar exatamente o valor recebido.
+		allocations[len(allocations)-1].Capital += left
+
+		rec := newPaymentRecord(l, allocations, payDate, user)
+		rec.RequestID = req.RequestID
+		rec.Type = paymentRecordType(rec.CapitalPaid, rec.InterestPaid)
+		rec.Note = req.Note
+		if rec.Note == "" {
+			rec.Note = fmt.Sprintf("Baixa pelo servidor. Ref: %s", payDate.Format("02/01/2006"))
+		}
+		appendPaymentRecord(&l, rec)
+		refreshLoanState(&l, today())
+
+		err = saveLoanVersioned(ctx, &l)
+		if err == errLoanConflict {
+			continue
+		}
+		if err != nil {
+			return l, false, err
+		}
+		logUserAction("BAIXA DE PAGAMENTO", user, fmt.Sprintf("Contrato %s: R$ %s (Capital: R$ %s, Juros: R$ %s, Encargos: R$ %s)",
+			l.ID, rec.Amount, rec.CapitalPaid, rec.InterestPaid, rec.FinePaid+rec.MoraPaid+rec.CorrectionPaid))
+		return l, true, nil
+	}
+	return Loan{}, false, errLoanConflict
+}
+
+func loanPaymentsHandler(w http.ResponseWriter, r *http.Request, id string, action []string) {
+	if len(action) == 3 && action[2] == "reverse" {
+		loanPaymentReverseHandler(w, r, id, action[1])
+		return
+	}
+	if len(action) > 1 {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var req paymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	order := req.Order
+	if len(order) == 0 {
+		order = loadSettings(ctx).System.PaymentAllocationOrder
+	}
+	order, err := normalizeAllocationOrder(order)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, created, err := registerPayment(ctx, id, req, order, currentUser(r))
+	switch err {
+	case nil:
+	case errLoanNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errInvalidAmount, errInvalidPaymentDate:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errPaymentExceedsBalance, errLoanWrittenOff:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case errLoanConflict:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(l)
+}
+
+// --- Estorno de Pagamentos ---
+
+// reversePayment estorna o lançamento de índice n do histórico (base 0, a mesma
+// posição do array history do front). O original é marcado como estornado e um
+// lançamento compensatório com valores negativos é anexado; totais, cronograma,
+// vencimento e situação são recalculados.
+func reversePayment(ctx context.Context, id string, n int, reason string, user string) (Loan, error) {
+	if strings.TrimSpace(reason) == "" {
+		return Loan{}, errMissingJustification
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		l, err := findLoan(ctx, id)
+		if err != nil {
+			return l, err
+		}
+		if n < 0 || n >= len(l.History) {
+			return l, errPaymentNotFound
+		}
+		orig := l.History[n]
+		if orig.Reversed {
+			return l, errAlreadyReversed
+		}
+		if orig.ReversalOf != nil || orig.Amount <= 0 {
+			return l, errNotReversible
+		}
+		// Reabrir o contrato quitado deixaria a mesma dívida no contrato novo também.
+		if l.RefinancedBy != "" || orig.Type == "Refinanciamento" {
+			return l, errRefinancedReversal
+		}
+
+		comp := PaymentRecord{
+			Date:            today().Format(finance.DateLayout),
+			Amount:          -orig.Amount,
+			CapitalPaid:     -orig.CapitalPaid,
+			InterestPaid:    -orig.InterestPaid,
+			FinePaid:        -orig.FinePaid,
+			MoraPaid:        -orig.MoraPaid,
+			CorrectionPaid:  -orig.CorrectionPaid,
+			Type:            "Estorno",
+			Note:            fmt.Sprintf("Estorno do lançamento de %s (%s)", orig.Date, orig.Type),
+			RegisteredAt:    time.Now().Format(time.RFC3339),
+			OriginalDueDate: l.NextDue,
+			User:            user,
+			ReversalOf:      &n,
+			Reason:          reason,
+		}
+		l.History[n].Reversed = true
+		l.History = append(l.History, comp)
+		l.TotalPaidCapital = max(0, l.TotalPaidCapital-orig.CapitalPaid)
+		l.TotalPaidInterest = max(0, l.TotalPaidInterest-orig.InterestPaid)
+		l.TotalPaidCharges = max(0, l.TotalPaidCharges-orig.FinePaid-orig.MoraPaid-orig.CorrectionPaid)
+		if err := ensureLoanSchedule(&l); err != nil {
+			return l, err
+		}
+		refreshLoanState(&l, today())
+
+		err = saveLoanVersioned(ctx, &l)
+		if err == errLoanConflict {
+			continue
+		}
+		if err != nil {
+			return l, err
+		}
+		logUserAction("ESTORNO DE PAGAMENTO", user, fmt.Sprintf("Contrato %s: estornou R$ %s (%s de %s). Justificativa: %s",
+			l.ID, orig.Amount, orig.Type, orig.Date, reason))
+		return l, nil
+	}
+	return Loan{}, errLoanConflict
+}
+
+func loanPaymentReverseHandler(w http.ResponseWriter, r *http.Request, id string, index string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	n, err := strconv.Atoi(index)
+	if err != nil {
+		http.Error(w, errPaymentNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	var body struct {
+		Justification string `json:"justification"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	l, err := reversePayment(ctx, id, n, body.Justification, currentUser(r))
+	switch err {
+	case nil:
+	case errMissingJustification:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errLoanNotFound, errPaymentNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errAlreadyReversed, errLoanConflict:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errNotReversible, errRefinancedReversal:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l)
+}
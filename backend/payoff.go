@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jules-playground/lms-backend/finance"
+	"github.com/jules-playground/lms-backend/money"
+)
+
+// --- Encargos de Atraso e Saldo para Quitação ---
+
+// PayoffInstallment é o valor em aberto de uma parcela na data de referência.
+type PayoffInstallment struct {
+	Agreement  string      `json:"agreement,omitempty"`
+	Number     int         `json:"number"`
+	DueDate    string      `json:"dueDate"`
+	DaysLate   int         `json:"daysLate"`
+	Capital    money.Cents `json:"capital"`
+	Interest   money.Cents `json:"interest"`
+	Fine       money.Cents `json:"fine"`
+	Mora       money.Cents `json:"mora"`
+	Correction money.Cents `json:"correction,omitempty"`
+	Total      money.Cents `json:"total"`
+}
+
+// Payoff resume o que o cliente deve na data: o vencido (com multa e mora) e o
+// saldo total das parcelas em aberto, sem desconto de juros futuros.
+type Payoff struct {
+	LoanID           string              `json:"loanId"`
+	Date             string              `json:"date"`
+	MaxDaysLate      int                 `json:"maxDaysLate"`
+	OverdueCapital   money.Cents         `json:"overdueCapital"`
+	OverdueInterest  money.Cents         `json:"overdueInterest"`
+	Fine             money.Cents         `json:"fine"`
+	Mora             money.Cents         `json:"mora"`
+	Correction       money.Cents         `json:"correction"` // correção monetária das parcelas vencidas
+	OverdueTotal     money.Cents         `json:"overdueTotal"`
+	OutstandingTotal money.Cents         `json:"outstandingTotal"`
+	NextInstallment  *PayoffInstallment  `json:"nextInstallment,omitempty"`
+	Installments     []PayoffInstallment `json:"installments"`
+	// Saldo corrigido por índice (contrato e acordos), somando todas as parcelas em aberto
+	Corrections []IndexCorrection `json:"corrections"`
+}
+
+// buildPayoff calcula os encargos parcela a parcela na data de referência
+// (calendário de São Paulo), incluindo parcelas de acordos ativos. Só parcelas
+// vencidas antes da data geram multa e mora; vencimento em feriado ou fim de
+// semana ganha até o dia útil seguinte (ver isOverdue).
+func buildPayoff(l Loan, ref time.Time) (Payoff, error) {
+	if err := ensureLoanSchedule(&l); err != nil {
+		return Payoff{}, err
+	}
+	applyPaymentsToSchedule(&l, ref)
+
+	p := Payoff{LoanID: l.ID, Date: ref.Format(finance.DateLayout), Installments: []PayoffInstallment{}, Corrections: []IndexCorrection{}}
+	for _, in := range openInstallments(l) {
+		fine, mora := installmentCharges(l, in.LoanInstallment, ref)
+		item := PayoffInstallment{
+			Agreement: in.Agreement,
+			Number:    in.Number,
+			DueDate:   in.DueDate,
+			DaysLate:  chargeableDaysLate(in.DueDate, ref),
+			Capital:   in.ExpectedCapital - in.PaidCapital,
+			Interest:  in.ExpectedInterest - in.PaidInterest,
+			Fine:      fine,
+			Mora:      mora,
+		}
+		if c, ok := correctionDetail(l, in, ref); ok {
+			item.Correction = c.Correction
+			p.Corrections = addCorrection(p.Corrections, c)
+		}
+		item.Total = item.Capital + item.Interest + item.Fine + item.Mora + item.Correction
+		p.OutstandingTotal += item.Total
+
+		if item.DaysLate > 0 {
+			p.MaxDaysLate = max(p.MaxDaysLate, item.DaysLate)
+			p.OverdueCapital += item.Capital
+			p.OverdueInterest += item.Interest
+			p.Fine += item.Fine
+			p.Mora += item.Mora
+			p.Correction += item.Correction
+			p.OverdueTotal += item.Total
+			p.Installments = append(p.Installments, item)
+		} else if p.NextInstallment == nil {
+			next := item
+			p.NextInstallment = &next
+		}
+	}
+	return p, nil
+}
+
+// parseRefDate lê o parâmetro ?date= e usa o dia corrente em São Paulo quando ausente.
+func parseRefDate(r *http.Request) (time.Time, error) {
+	if d := r.URL.Query().Get("date"); d != "" {
+		return parseLoanDate(d)
+	}
+	return today(), nil
+}
+
+func loanPayoffHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ref, err := parseRefDate(r)
+	if err != nil {
+		http.Error(w, "Data inválida", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	l, err := findLoan(ctx, id)
+	if err == errLoanNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Erro ao buscar contrato", http.StatusInternalServerError)
+		return
+	}
+	p, err := buildPayoff(l, ref)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// reminderValues devolve valor, dias de atraso e vencimento usados na mensagem de
+// cobrança, calculados pelo mesmo motor da tela de quitação.
+func reminderValues(l Loan) (money.Cents, int, string, error) {
+	p, err := buildPayoff(l, today())
+	if err != nil {
+		return 0, 0, "", err
+	}
+	if p.OverdueTotal > 0 {
+		return p.OverdueTotal, p.MaxDaysLate, formatBRDate(p.Installments[0].DueDate), nil
+	}
+	if p.NextInstallment != nil {
+		return p.NextInstallment.Total, 0, formatBRDate(p.NextInstallment.DueDate), nil
+	}
+	return 0, 0, formatBRDate(l.NextDue), nil
+}
+
+func formatBRDate(s string) string {
+	d, err := parseLoanDate(s)
+	if err != nil {
+		return s
+	}
+	return d.Format("02/01/2006")
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jules-playground/lms-backend/finance"
+	"github.com/jules-playground/lms-backend/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// --- Refinanciamento ---
+
+var (
+	errInvalidRefinance  = errors.New("Informe o número de parcelas e valor novo maior ou igual a zero")
+	errAlreadyRefinanced = errors.New("Contrato já foi refinanciado")
+	errRefinanceApproval = errors.New("Com a exigência de proposta aprovada ligada, o refinanciamento só rola o saldo; dinheiro novo precisa de proposta (/api/loan-applications)")
+)
+
+// refinanceRequest descreve o contrato novo. Taxa, periodicidade, tipo de juros e
+// regra de vencimento vazios repetem os do contrato quitado.
+type refinanceRequest struct {
+	NewMoney     money.Cents `json:"newMoney"` // dinheiro novo liberado além do saldo rolado
+	InterestRate float64     `json:"interestRate"`
+	Installments int         `json:"installments"`
+	Frequency    string      `json:"frequency"`
+	InterestType string      `json:"interestType"`
+	FirstDueDate string      `json:"firstDueDate"`
+	DueDateRule  string      `json:"dueDateRule"`
+	GraceDays    int         `json:"graceDays"`
+	GraceRule    string      `json:"graceRule"`
+	AffiliateFee money.Cents `json:"affiliateFee"`
+	Note         string      `json:"note"`
+	RequestID    string      `json:"requestId"`
+
+	CreditLimitOverride *CreditLimitOverride `json:"creditLimitOverride"` // liberação de ADMIN acima do limite
+}
+
+type refinanceResult struct {
+	Closed Loan            `json:"closed"` // contrato antigo, quitado
+	Loan   Loan            `json:"loan"`   // contrato novo
+	Quote  SettlementQuote `json:"quote"`  // cotação usada para quitar o antigo
+}
+
+// refinanceLoan quita o contrato pela cotação de quitação antecipada do dia e
+// abre um contrato novo no valor do saldo quitado mais o dinheiro novo, para o
+// mesmo cliente, garantidor e afiliado. O contrato novo é gravado primeiro; se o
+// antigo tiver mudado nesse meio tempo o novo é desfeito e a operação refeita.
+// Passa pela lista negra e pelo limite de crédito como um contrato novo. Com a
+// exigência de proposta aprovada, só a rolagem do saldo dispensa proposta: não
+// há crédito novo a aprovar.
+func refinanceLoan(ctx context.Context, id string, req refinanceRequest, user string) (refinanceResult, bool, error) {
+	if req.Installments <= 0 || req.NewMoney < 0 || req.InterestRate < 0 {
+		return refinanceResult{}, false, errInvalidRefinance
+	}
+	if req.NewMoney > 0 && loadSettings(ctx).System.loanApprovalRequired() {
+		return refinanceResult{}, false, errRefinanceApproval
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		old, err := findLoan(ctx, id)
+		if err != nil {
+			return refinanceResult{}, false, err
+		}
+		if old.RefinancedBy != "" {
+			for _, h := range old.History {
+				if req.RequestID != "" && h.RequestID == req.RequestID {
+					n, err := findLoan(ctx, old.RefinancedBy)
+					return refinanceResult{Closed: old, Loan: n}, false, err
+				}
+			}
+			return refinanceResult{}, false, errAlreadyRefinanced
+		}
+		if err := ensureLoanSchedule(&old); err != nil {
+			return refinanceResult{}, false, err
+		}
+		ref := today()
+		q, allocations, err := buildSettlementQuote(old, ref)
+		if err != nil {
+			return refinanceResult{}, false, err
+		}
+
+		n := Loan{
+			ID:                 primitive.NewObjectID().Hex(),
+			Client:             old.Client,
+			Amount:             q.Total + req.NewMoney,
+			Installments:       req.Installments,
+			InterestRate:       req.InterestRate,
+			StartDate:          ref.Format(finance.DateLayout),
+			NextDue:            req.FirstDueDate,
+			Status:             "Em Dia",
+			FineRate:           old.FineRate,
+			MoraInterestRate:   old.MoraInterestRate,
+			ClientBank:         old.ClientBank,
+			PaymentMethod:      old.PaymentMethod,
+			History:            []PaymentRecord{},
+			InterestType:       req.InterestType,
+			Frequency:          req.Frequency,
+			DueDateRule:        req.DueDateRule,
+			GraceDays:          req.GraceDays,
+			GraceRule:          req.GraceRule,
+			CorrectionIndex:    old.CorrectionIndex,
+			GuarantorName:      old.GuarantorName,
+			GuarantorCPF:       old.GuarantorCPF,
+			GuarantorCPFDigits: old.GuarantorCPFDigits,
+			GuarantorAddress:   old.GuarantorAddress,
+			AffiliateID:        old.AffiliateID,
+			AffiliateCode:      old.AffiliateCode,
+			AffiliateName:      old.AffiliateName,
+			AffiliateFee:       req.AffiliateFee,
+			AffiliateNotes:     old.AffiliateNotes,
+			RefinancedFrom:     old.ID,
+			Justification:      req.Note,
+		}
+		n.CreditLimitOverride = req.CreditLimitOverride
+		if n.InterestRate == 0 {
+			n.InterestRate = old.InterestRate
+		}
+		if n.Frequency == "" {
+			n.Frequency = old.Frequency
+		}
+		if n.InterestType == "" {
+			n.InterestType = old.InterestType
+		}
+		if n.DueDateRule == "" {
+			n.DueDateRule = old.DueDateRule
+		}
+		// O contrato novo continua vinculado ao afiliado, mas a originação só
+		// comissiona o dinheiro novo: o saldo rolado já foi pago no antigo. A
+		// comissão digitada só vale para os contratos antigos, sem vínculo.
+		if old.AffiliateCommission != nil {
+			terms := old.AffiliateCommission.ForRollover(q.Total)
+			n.AffiliateCommission = &terms
+		}
+		if err := prepareNewLoan(ctx, &n); err != nil {
+			return refinanceResult{}, false, err
+		}
+		if err := screenLoanBlacklist(ctx, &n, "Refinanciamento", user); err != nil {
+			return refinanceResult{}, false, err
+		}
+		if err := checkCreditLimit(ctx, &n, user); err != nil {
+			return refinanceResult{}, false, err
+		}
+		n.refreshAffiliateFee()
+		if _, err := loanCollection.InsertOne(ctx, n); err != nil {
+			return refinanceResult{}, false, err
+		}
+
+		rec := newPaymentRecord(old, allocations, ref, user)
+		rec.RequestID = req.RequestID
+		rec.Type = "Refinanciamento"
+		rec.Note = fmt.Sprintf("Quitado por refinanciamento no contrato %s. Desconto de juros futuros: R$ %s", n.ID, q.InterestDiscount)
+		appendPaymentRecord(&old, rec)
+		old.RefinancedBy = n.ID
+		refreshLoanState(&old, ref)
+
+		err = saveLoanVersioned(ctx, &old)
+		if err != nil {
+			loanCollection.DeleteOne(ctx, bson.M{"id": n.ID})
+			if err == errLoanConflict {
+				continue
+			}
+			return refinanceResult{}, false, err
+		}
+		logUserAction("REFINANCIAMENTO", user, fmt.Sprintf("Contrato %s (%s) quitado com R$ %s e refinanciado no contrato %s: R$ %s (dinheiro novo R$ %s) em %dx",
+			old.ID, old.Client, q.Total, n.ID, n.Amount, req.NewMoney, n.Installments))
+		return refinanceResult{Closed: old, Loan: n, Quote: q}, true, nil
+	}
+	return refinanceResult{}, false, errLoanConflict
+}
+
+func loanRefinanceHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req refinanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	res, created, err := refinanceLoan(ctx, id, req, currentUser(r))
+	if blocked, ok := err.(blacklistBlockedError); ok {
+		writeFieldErrors(w, http.StatusUnprocessableEntity, blocked.fields())
+		return
+	}
+	if _, ok := err.(creditLimitError); ok {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	switch err {
+	case nil:
+	case errLoanNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errInvalidRefinance, errInvalidPaymentDate, errInvalidDueDateRule, errInvalidIndex,
+		finance.ErrInvalidGraceDays, finance.ErrInvalidGraceRule,
+		finance.ErrInvalidAmount, finance.ErrInvalidInstallments, finance.ErrInvalidRate, finance.ErrInvalidType, finance.ErrInvalidFrequency:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errNothingToSettle, errLoanWrittenOff:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case errRefinanceApproval:
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case errAlreadyRefinanced, errLoanConflict:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,125 @@
+// Package score calcula a nota interna de crédito do cliente a partir do
+// histórico dos contratos dele na própria base.
+package score
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Pontuação máxima de cada fator; a soma é MaxScore.
+const (
+	MaxPunctuality  = 400 // parcelas pagas até o vencimento
+	MaxDaysLate     = 200 // atraso médio das parcelas pagas ou vencidas
+	MaxAgreements   = 150 // acordos de renegociação
+	MaxWriteOffs    = 150 // contratos baixados como perda
+	MaxRelationship = 100 // tempo como cliente
+
+	MaxScore = MaxPunctuality + MaxDaysLate + MaxAgreements + MaxWriteOffs + MaxRelationship
+)
+
+// Parâmetros da escala de cada fator.
+const (
+	daysLatePenalty    = 10 // pontos perdidos por dia de atraso médio
+	agreementPenalty   = 75 // pontos perdidos por acordo
+	relationshipMonths = 24 // meses para pontuação máxima de relacionamento
+)
+
+// Input resume o histórico do cliente.
+type Input struct {
+	Installments int       // parcelas pagas ou vencidas em aberto
+	OnTime       int       // parcelas pagas até o vencimento
+	DaysLate     int       // soma dos dias de atraso dessas parcelas
+	Agreements   int       // acordos feitos
+	WriteOffs    int       // contratos baixados como perda
+	ClientSince  time.Time // início do primeiro contrato; zero se não houver
+}
+
+// Factor é a contribuição de um critério para a nota.
+type Factor struct {
+	Name   string `json:"name" bson:"name"`
+	Points int    `json:"points" bson:"points"`
+	Max    int    `json:"max" bson:"max"`
+	Detail string `json:"detail" bson:"detail"`
+}
+
+// Result é a nota com a composição por fator.
+type Result struct {
+	Score   int      `json:"score" bson:"score"`
+	Grade   string   `json:"grade" bson:"grade"`
+	Factors []Factor `json:"factors" bson:"factors"`
+}
+
+// Calculate pontua o cliente na data de referência. Sem parcelas avaliáveis,
+// pontualidade e atraso recebem metade dos pontos (cliente novo, neutro).
+func Calculate(in Input, ref time.Time) Result {
+	punctuality := Factor{Name: "Pontualidade", Max: MaxPunctuality}
+	lateness := Factor{Name: "Dias de atraso", Max: MaxDaysLate}
+	if in.Installments == 0 {
+		punctuality.Points, punctuality.Detail = MaxPunctuality/2, "Sem parcelas vencidas"
+		lateness.Points, lateness.Detail = MaxDaysLate/2, "Sem parcelas vencidas"
+	} else {
+		ratio := float64(in.OnTime) / float64(in.Installments)
+		punctuality.Points = int(math.Round(MaxPunctuality * ratio))
+		punctuality.Detail = fmt.Sprintf("%d de %d parcelas em dia", in.OnTime, in.Installments)
+		avg := float64(in.DaysLate) / float64(in.Installments)
+		lateness.Points = clamp(int(math.Round(MaxDaysLate-daysLatePenalty*avg)), 0, MaxDaysLate)
+		lateness.Detail = fmt.Sprintf("Atraso médio de %.1f dia(s) por parcela", avg)
+	}
+
+	agreements := Factor{
+		Name:   "Acordos",
+		Max:    MaxAgreements,
+		Points: clamp(MaxAgreements-agreementPenalty*in.Agreements, 0, MaxAgreements),
+		Detail: fmt.Sprintf("%d acordo(s) de renegociação", in.Agreements),
+	}
+
+	writeOffs := Factor{Name: "Perdas", Max: MaxWriteOffs, Points: MaxWriteOffs, Detail: "Nenhum contrato baixado como perda"}
+	if in.WriteOffs > 0 {
+		writeOffs.Points = 0
+		writeOffs.Detail = fmt.Sprintf("%d contrato(s) baixado(s) como perda", in.WriteOffs)
+	}
+
+	relationship := Factor{Name: "Tempo de relacionamento", Max: MaxRelationship, Detail: "Sem contratos anteriores"}
+	if !in.ClientSince.IsZero() && ref.After(in.ClientSince) {
+		months := monthsBetween(in.ClientSince, ref)
+		relationship.Points = MaxRelationship * min(months, relationshipMonths) / relationshipMonths
+		relationship.Detail = fmt.Sprintf("Cliente há %d mês(es)", months)
+	}
+
+	r := Result{Factors: []Factor{punctuality, lateness, agreements, writeOffs, relationship}}
+	for _, f := range r.Factors {
+		r.Score += f.Points
+	}
+	r.Grade = Grade(r.Score)
+	return r
+}
+
+// Grade converte a nota em faixa de risco (A é o menor risco).
+func Grade(score int) string {
+	switch {
+	case score >= 800:
+		return "A"
+	case score >= 650:
+		return "B"
+	case score >= 500:
+		return "C"
+	case score >= 350:
+		return "D"
+	default:
+		return "E"
+	}
+}
+
+func monthsBetween(from, to time.Time) int {
+	m := (to.Year()-from.Year())*12 + int(to.Month()-from.Month())
+	if to.Day() < from.Day() {
+		m--
+	}
+	return max(m, 0)
+}
+
+func clamp(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
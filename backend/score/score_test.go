@@ -0,0 +1,76 @@
+package score
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalculate(t *testing.T) {
+	ref := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		in        Input
+		wantScore int
+		wantGrade string
+	}{
+		{
+			// neutro: 200 + 100 + 150 + 150 + 0
+			name:      "cliente novo",
+			in:        Input{},
+			wantScore: 600,
+			wantGrade: "C",
+		},
+		{
+			// tudo em dia há mais de dois anos
+			name:      "bom pagador",
+			in:        Input{Installments: 24, OnTime: 24, ClientSince: ref.AddDate(-3, 0, 0)},
+			wantScore: 1000,
+			wantGrade: "A",
+		},
+		{
+			// 6/12 em dia = 200; atraso médio 5 dias = 150; 1 acordo = 75; 12 meses = 50
+			name:      "atrasos e acordo",
+			in:        Input{Installments: 12, OnTime: 6, DaysLate: 60, Agreements: 1, ClientSince: ref.AddDate(-1, 0, 0)},
+			wantScore: 625,
+			wantGrade: "C",
+		},
+		{
+			// 0 em dia; atraso médio de 40 dias zera; 3 acordos zeram; perda zera; 6 meses = 25
+			name:      "perda",
+			in:        Input{Installments: 4, DaysLate: 160, Agreements: 3, WriteOffs: 1, ClientSince: ref.AddDate(0, -6, 0)},
+			wantScore: 25,
+			wantGrade: "E",
+		},
+	}
+	for _, tt := range tests {
+		got := Calculate(tt.in, ref)
+		if got.Score != tt.wantScore || got.Grade != tt.wantGrade {
+			t.Errorf("%s: Calculate = %d (%s), want %d (%s)", tt.name, got.Score, got.Grade, tt.wantScore, tt.wantGrade)
+		}
+		sum, max := 0, 0
+		for _, f := range got.Factors {
+			sum += f.Points
+			max += f.Max
+			if f.Points < 0 || f.Points > f.Max {
+				t.Errorf("%s: fator %s = %d fora de 0..%d", tt.name, f.Name, f.Points, f.Max)
+			}
+		}
+		if sum != got.Score || max != MaxScore {
+			t.Errorf("%s: soma dos fatores = %d/%d, want %d/%d", tt.name, sum, max, got.Score, MaxScore)
+		}
+	}
+}
+
+func TestGrade(t *testing.T) {
+	tests := []struct {
+		score int
+		want  string
+	}{
+		{1000, "A"}, {800, "A"}, {799, "B"}, {650, "B"}, {500, "C"}, {350, "D"}, {349, "E"}, {0, "E"},
+	}
+	for _, tt := range tests {
+		if got := Grade(tt.score); got != tt.want {
+			t.Errorf("Grade(%d) = %s, want %s", tt.score, got, tt.want)
+		}
+	}
+}
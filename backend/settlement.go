@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jules-playground/lms-backend/finance"
+	"github.com/jules-playground/lms-backend/money"
+)
+
+// --- Quitação Antecipada ---
+
+var (
+	errNothingToSettle  = errors.New("Contrato não possui saldo a quitar")
+	errSettlementAmount = errors.New("Valor informado difere do valor da cotação de quitação")
+)
+
+// SettlementLine é a composição de uma parcela em aberto na quitação.
+type SettlementLine struct {
+	Agreement        string      `json:"agreement,omitempty"`
+	Number           int         `json:"number"`
+	DueDate          string      `json:"dueDate"`
+	Capital          money.Cents `json:"capital"`
+	Interest         money.Cents `json:"interest"`
+	InterestDiscount money.Cents `json:"interestDiscount"`
+	Fine             money.Cents `json:"fine"`
+	Mora             money.Cents `json:"mora"`
+	Correction       money.Cents `json:"correction,omitempty"`
+	Total            money.Cents `json:"total"`
+}
+
+// SettlementQuote é a cotação para quitar o contrato na data informada.
+type SettlementQuote struct {
+	LoanID           string           `json:"loanId"`
+	Date             string           `json:"date"`
+	ExpiresAt        string           `json:"expiresAt"`
+	RemainingCapital money.Cents      `json:"remainingCapital"`
+	Interest         money.Cents      `json:"interest"`
+	InterestDiscount money.Cents      `json:"interestDiscount"`
+	Fine             money.Cents      `json:"fine"`
+	Mora             money.Cents      `json:"mora"`
+	Correction       money.Cents      `json:"correction"`
+	Total            money.Cents      `json:"total"`
+	Lines            []SettlementLine `json:"lines"`
+}
+
+// installmentPeriodStart devolve o início do período de juros da parcela: o
+// vencimento anterior, ou a data do contrato/acordo para a primeira parcela.
+func installmentPeriodStart(l Loan, in openInstallment) (time.Time, error) {
+	items, start := l.Schedule, l.StartDate
+	for _, a := range l.Agreements {
+		if a.ID == in.Agreement {
+			items, start = a.Schedule, a.CreatedAt
+		}
+	}
+	for i := range items {
+		if items[i].Number == in.Number && i > 0 {
+			return parseLoanDate(items[i-1].DueDate)
+		}
+	}
+	return parseLoanDate(start)
+}
+
+// buildSettlementQuote calcula a quitação na data de referência: capital em aberto,
+// juros das parcelas vencidas, juros pró-rata da parcela corrente, encargos de
+// atraso e correção monetária. Juros de períodos futuros são abatidos (CDC art.
+// 52 §2º). Devolve também as alocações que quitam cada parcela.
+func buildSettlementQuote(l Loan, ref time.Time) (SettlementQuote, []PaymentAllocation, error) {
+	if l.WriteOff != nil {
+		return SettlementQuote{}, nil, errLoanWrittenOff
+	}
+	if err := ensureLoanSchedule(&l); err != nil {
+		return SettlementQuote{}, nil, err
+	}
+	applyPaymentsToSchedule(&l, ref)
+
+	y, m, d := ref.Date()
+	q := SettlementQuote{
+		LoanID:    l.ID,
+		Date:      ref.Format(finance.DateLayout),
+		ExpiresAt: time.Date(y, m, d, 23, 59, 59, 0, saoPaulo).Format(time.RFC3339),
+		Lines:     []SettlementLine{},
+	}
+	var allocations []PaymentAllocation
+	for _, in := range openInstallments(l) {
+		fine, mora := installmentCharges(l, in.LoanInstallment, ref)
+		capital := in.ExpectedCapital - in.PaidCapital
+		openInterest := in.ExpectedInterest - in.PaidInterest - in.InterestDiscount
+
+		interest := openInterest
+		if daysLate(in.DueDate, ref) < 0 {
+			due, _ := parseLoanDate(in.DueDate)
+			start, err := installmentPeriodStart(l, in)
+			if err != nil {
+				start = due
+			}
+			y, m, d := ref.Date()
+			accrued := money.FromFloat(finance.ProRata(in.ExpectedInterest.Float(), start, due, time.Date(y, m, d, 0, 0, 0, 0, time.UTC)))
+			interest = min(openInterest, max(0, accrued-in.PaidInterest))
+		}
+
+		line := SettlementLine{
+			Agreement:        in.Agreement,
+			Number:           in.Number,
+			DueDate:          in.DueDate,
+			Capital:          capital,
+			Interest:         interest,
+			InterestDiscount: openInterest - interest,
+			Fine:             fine,
+			Mora:             mora,
+			Correction:       installmentCorrection(l, in, ref),
+		}
+		line.Total = line.Capital + line.Interest + line.Fine + line.Mora + line.Correction
+		q.Lines = append(q.Lines, line)
+		q.RemainingCapital += line.Capital
+		q.Interest += line.Interest
+		q.InterestDiscount += line.InterestDiscount
+		q.Fine += line.Fine
+		q.Mora += line.Mora
+		q.Correction += line.Correction
+		q.Total += line.Total
+
+		allocations = append(allocations, PaymentAllocation{
+			Agreement:   in.Agreement,
+			Installment: in.Number,
+			Fine:        line.Fine,
+			Mora:        line.Mora,
+			Correction:  line.Correction,
+			Interest:    line.Interest,
+			Capital:     line.Capital,
+			Discount:    line.InterestDiscount,
+		})
+	}
+	if len(q.Lines) == 0 {
+		return q, nil, errNothingToSettle
+	}
+	return q, allocations, nil
+}
+
+type settlementRequest struct {
+	Date      string      `json:"date"`
+	Amount    money.Cents `json:"amount"`
+	Note      string      `json:"note"`
+	RequestID string      `json:"requestId"`
+}
+
+// settleLoan registra a quitação pelo valor da cotação e encerra o contrato como "Quitado".
+func settleLoan(ctx context.Context, id string, req settlementRequest, user string) (Loan, bool, error) {
+	payDate := today()
+	if req.Date != "" {
+		d, err := parseLoanDate(req.Date)
+		if err != nil {
+			return Loan{}, false, errInvalidPaymentDate
+		}
+		payDate = d
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		l, err := findLoan(ctx, id)
+		if err != nil {
+			return l, false, err
+		}
+		if req.RequestID != "" {
+			for _, h := range l.History {
+				if h.RequestID == req.RequestID {
+					return l, false, nil
+				}
+			}
+		}
+		if err := ensureLoanSchedule(&l); err != nil {
+			return l, false, err
+		}
+		q, allocations, err := buildSettlementQuote(l, payDate)
+		if err != nil {
+			return l, false, err
+		}
+		if req.Amount > 0 && max(req.Amount-q.Total, q.Total-req.Amount) > paidTolerance {
+			return l, false, errSettlementAmount
+		}
+
+		rec := newPaymentRecord(l, allocations, payDate, user)
+		rec.RequestID = req.RequestID
+		rec.Type = "Quitação"
+		rec.Note = fmt.Sprintf("Quitação antecipada. Desconto de juros futuros: R$ %s", q.InterestDiscount)
+		if req.Note != "" {
+			rec.Note += ". " + req.Note
+		}
+		appendPaymentRecord(&l, rec)
+		refreshLoanState(&l, today())
+
+		err = saveLoanVersioned(ctx, &l)
+		if err == errLoanConflict {
+			continue
+		}
+		if err != nil {
+			return l, false, err
+		}
+		logUserAction("QUITAÇÃO ANTECIPADA", user, fmt.Sprintf("Contrato %s (%s): quitado com R$ %s (desconto de juros R$ %s)",
+			l.ID, l.Client, q.Total, q.InterestDiscount))
+		return l, true, nil
+	}
+	return Loan{}, false, errLoanConflict
+}
+
+func loanSettlementHandler(w http.ResponseWriter, r *http.Request, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		ref, err := parseRefDate(r)
+		if err != nil {
+			http.Error(w, "Data inválida", http.StatusBadRequest)
+			return
+		}
+		l, err := findLoan(ctx, id)
+		if err == errLoanNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Erro ao buscar contrato", http.StatusInternalServerError)
+			return
+		}
+		q, _, err := buildSettlementQuote(l, ref)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(q)
+	case http.MethodPost:
+		var req settlementRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		l, created, err := settleLoan(ctx, id, req, currentUser(r))
+		switch err {
+		case nil:
+		case errLoanNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errInvalidPaymentDate:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errNothingToSettle, errSettlementAmount, errLoanWrittenOff:
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case errLoanConflict:
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(l)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jules-playground/lms-backend/finance"
+	"github.com/jules-playground/lms-backend/money"
+)
+
+// --- Simulação de Empréstimo ---
+
+type simulationRequest struct {
+	Amount       money.Cents `json:"amount"`
+	InterestRate float64     `json:"interestRate"`
+	Installments int         `json:"installments"`
+	Frequency    string      `json:"frequency"`
+	InterestType string      `json:"interestType"`
+	StartDate    string      `json:"startDate"`
+	FirstDueDate string      `json:"firstDueDate"`
+	DueDateRule  string      `json:"dueDateRule"`
+	GraceDays    int         `json:"graceDays"`
+	GraceRule    string      `json:"graceRule"`
+}
+
+// Simulation é a cotação de um contrato. É o mesmo cálculo gravado pelo
+// loansHandler na criação, então o contrato nunca diverge da simulação.
+type Simulation struct {
+	StartDate        string           `json:"startDate"`
+	FirstDueDate     string           `json:"firstDueDate"`
+	DueDateRule      string           `json:"dueDateRule"`
+	GraceDays        int              `json:"graceDays,omitempty"`
+	GraceRule        string           `json:"graceRule,omitempty"`
+	GraceInterest    money.Cents      `json:"graceInterest,omitempty"`
+	InstallmentValue money.Cents      `json:"installmentValue"`
+	ProjectedProfit  money.Cents      `json:"projectedProfit"`
+	TotalPayable     money.Cents      `json:"totalPayable"`
+	IOFDaily         money.Cents      `json:"iofDaily"`
+	IOFAdditional    money.Cents      `json:"iofAdditional"`
+	IOF              money.Cents      `json:"iof"`
+	CET              float64          `json:"cet"`        // % ao ano
+	CETMonthly       float64          `json:"cetMonthly"` // % ao mês equivalente
+	Schedule         finance.Schedule `json:"schedule"`
+}
+
+// simulateLoan roda o motor de amortização. Sem data de início usa hoje; sem
+// primeiro vencimento usa um período após o início e a carência. Os juros da
+// carência entram nas parcelas pela regra informada (padrão: capitalizar). Os
+// vencimentos seguem a regra de dia útil do contrato (padrão: próximo dia útil). O IOF sai do
+// cronograma com as alíquotas das configurações e entra no CET como custo
+// retido na liberação.
+func simulateLoan(req simulationRequest, rates iofRates) (Simulation, error) {
+	rule, ok := normalizeDueDateRule(req.DueDateRule)
+	if !ok {
+		return Simulation{}, errInvalidDueDateRule
+	}
+	if req.GraceDays < 0 {
+		return Simulation{}, finance.ErrInvalidGraceDays
+	}
+	graceRule := ""
+	if req.GraceDays > 0 {
+		r, err := finance.NormalizeGraceRule(req.GraceRule)
+		if err != nil {
+			return Simulation{}, err
+		}
+		graceRule = r
+	}
+	start := today()
+	if req.StartDate != "" {
+		d, err := parseLoanDate(req.StartDate)
+		if err != nil {
+			return Simulation{}, errInvalidPaymentDate
+		}
+		start = d
+	}
+	first := finance.DueDate(time.Date(start.Year(), start.Month(), start.Day()+req.GraceDays, 0, 0, 0, 0, time.UTC), req.Frequency, 2)
+	if req.FirstDueDate != "" {
+		d, err := parseLoanDate(req.FirstDueDate)
+		if err != nil {
+			return Simulation{}, errInvalidPaymentDate
+		}
+		first = d
+	}
+
+	s, err := finance.Build(finance.Params{
+		Amount:       req.Amount.Float(),
+		InterestRate: req.InterestRate,
+		Installments: req.Installments,
+		Frequency:    req.Frequency,
+		InterestType: req.InterestType,
+		FirstDue:     first,
+		AdjustDue:    dueDateAdjuster(rule),
+		GraceDays:    req.GraceDays,
+		GraceRule:    graceRule,
+	})
+	if err != nil {
+		return Simulation{}, err
+	}
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	iof := finance.CalcIOF(req.Amount.Float(), startDay, s, rates.Daily, rates.Additional)
+	cet := finance.CET(req.Amount.Float()-iof.Total, startDay, s)
+	return Simulation{
+		StartDate:        startDay.Format(finance.DateLayout),
+		FirstDueDate:     s.Installments[0].DueDate,
+		DueDateRule:      rule,
+		GraceDays:        req.GraceDays,
+		GraceRule:        graceRule,
+		GraceInterest:    money.FromFloat(s.GraceInterest),
+		InstallmentValue: money.FromFloat(s.InstallmentValue),
+		ProjectedProfit:  money.FromFloat(s.TotalInterest),
+		TotalPayable:     money.FromFloat(s.TotalPayable),
+		IOFDaily:         money.FromFloat(iof.Daily),
+		IOFAdditional:    money.FromFloat(iof.Additional),
+		IOF:              money.FromFloat(iof.Total),
+		CET:              cet,
+		CETMonthly:       finance.MonthlyEquivalent(cet),
+		Schedule:         s,
+	}, nil
+}
+
+// applySimulation grava no contrato os valores calculados pelo servidor.
+func applySimulation(l *Loan, sim Simulation) {
+	l.InstallmentValue = sim.InstallmentValue
+	l.ProjectedProfit = sim.ProjectedProfit
+	l.CET = sim.CET
+	l.IOFDaily = sim.IOFDaily
+	l.IOFAdditional = sim.IOFAdditional
+	l.IOF = sim.IOF
+	l.InterestType = sim.Schedule.InterestType
+	l.Frequency = sim.Schedule.Frequency
+	l.DueDateRule = sim.DueDateRule
+	l.GraceDays = sim.GraceDays
+	l.GraceRule = sim.GraceRule
+	l.GraceInterest = sim.GraceInterest
+	l.NextDue = sim.FirstDueDate
+	l.Schedule = installmentsFromSchedule(sim.Schedule)
+}
+
+// simulateNewLoan cota um contrato novo pelo mesmo motor do /simulate, com as
+// alíquotas de IOF das configurações. Normaliza o índice de correção informado.
+func simulateNewLoan(ctx context.Context, l *Loan) (Simulation, error) {
+	if l.CorrectionIndex != "" {
+		index, ok := normalizeIndex(l.CorrectionIndex)
+		if !ok {
+			return Simulation{}, errInvalidIndex
+		}
+		l.CorrectionIndex = index
+	}
+	return simulateLoan(simulationRequest{
+		Amount:       l.Amount,
+		InterestRate: l.InterestRate,
+		Installments: l.Installments,
+		Frequency:    l.Frequency,
+		InterestType: l.InterestType,
+		StartDate:    l.StartDate,
+		FirstDueDate: l.NextDue,
+		DueDateRule:  l.DueDateRule,
+		GraceDays:    l.GraceDays,
+		GraceRule:    l.GraceRule,
+	}, loadSettings(ctx).System.iofRates())
+}
+
+// prepareNewLoan calcula parcela, lucro projetado, CET, IOF e cronograma de um
+// contrato novo. Usado na criação, no refinanciamento e na aprovação de propostas.
+func prepareNewLoan(ctx context.Context, l *Loan) error {
+	sim, err := simulateNewLoan(ctx, l)
+	if err != nil {
+		return err
+	}
+	applySimulation(l, sim)
+	applyPaymentsToSchedule(l, today())
+	return nil
+}
+
+func loanSimulateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req simulationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	sim, err := simulateLoan(req, loadSettings(ctx).System.iofRates())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sim)
+}
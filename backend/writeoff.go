@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jules-playground/lms-backend/document"
+	"github.com/jules-playground/lms-backend/finance"
+	"github.com/jules-playground/lms-backend/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// --- Baixa como Perda e Recuperação de Crédito ---
+
+// LoanWrittenOff é a situação do contrato baixado como perda. Não volta a ser
+// reavaliado pela rotina de status nem conta como ativo no dashboard.
+const LoanWrittenOff = "Perda"
+
+var (
+	errInvalidWriteOff   = errors.New("Informe o motivo da baixa")
+	errAlreadyWrittenOff = errors.New("Contrato já foi baixado como perda")
+	errLoanWrittenOff    = errors.New("Contrato baixado como perda; registre o valor como recuperação")
+	errNotWrittenOff     = errors.New("Recuperações só podem ser lançadas em contratos baixados como perda")
+	errNothingToWriteOff = errors.New("Contrato não possui saldo em aberto")
+)
+
+// WriteOff registra o saldo em aberto no momento da baixa.
+type WriteOff struct {
+	Date        string      `json:"date" bson:"date"`
+	User        string      `json:"user" bson:"user"`
+	Reason      string      `json:"reason" bson:"reason"`
+	Capital     money.Cents `json:"capital" bson:"capital"`
+	Interest    money.Cents `json:"interest" bson:"interest"`
+	Charges     money.Cents `json:"charges" bson:"charges"` // multa, mora e correção até a data da baixa
+	Blacklisted bool        `json:"blacklisted,omitempty" bson:"blacklisted,omitempty"`
+}
+
+// Recovery é um valor recebido depois da baixa. Fica fora do histórico de
+// pagamentos do cronograma para que o relatório mostre a perda líquida.
+type Recovery struct {
+	Date         string      `json:"date" bson:"date"`
+	Amount       money.Cents `json:"amount" bson:"amount"`
+	Note         string      `json:"note,omitempty" bson:"note,omitempty"`
+	User         string      `json:"user,omitempty" bson:"user,omitempty"`
+	RegisteredAt string      `json:"registeredAt" bson:"registeredAt"`
+	RequestID    string      `json:"requestId,omitempty" bson:"requestId,omitempty"`
+}
+
+type writeOffRequest struct {
+	Reason    string `json:"reason"`
+	Blacklist bool   `json:"blacklist"` // inclui o CPF do cliente na lista negra
+	RiskLevel string `json:"riskLevel"` // risco da inclusão (padrão: Alto)
+}
+
+// writeOffLoan baixa o saldo em aberto do contrato como perda.
+func writeOffLoan(ctx context.Context, id string, req writeOffRequest, user string) (Loan, error) {
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return Loan{}, errInvalidWriteOff
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		l, err := findLoan(ctx, id)
+		if err != nil {
+			return l, err
+		}
+		if l.WriteOff != nil {
+			return l, errAlreadyWrittenOff
+		}
+		if err := ensureLoanSchedule(&l); err != nil {
+			return l, err
+		}
+		ref := today()
+		applyPaymentsToSchedule(&l, ref)
+
+		w := WriteOff{Date: ref.Format(finance.DateLayout), User: user, Reason: req.Reason}
+		for _, in := range openInstallments(l) {
+			fine, mora := installmentCharges(l, in.LoanInstallment, ref)
+			w.Capital += in.ExpectedCapital - in.PaidCapital
+			w.Interest += max(0, in.ExpectedInterest-in.PaidInterest-in.InterestDiscount)
+			w.Charges += fine + mora + installmentCorrection(l, in, ref)
+		}
+		if w.Capital+w.Interest <= 0 {
+			return l, errNothingToWriteOff
+		}
+		l.WriteOff = &w
+		if req.Blacklist {
+			w.Blacklisted = blacklistWrittenOffClient(ctx, l, req.RiskLevel, user)
+		}
+		l.History = append(l.History, PaymentRecord{
+			Date:            w.Date,
+			Type:            "Perda",
+			Note:            fmt.Sprintf("BAIXA COMO PERDA: capital R$ %s, juros R$ %s, encargos R$ %s. %s", w.Capital, w.Interest, w.Charges, w.Reason),
+			RegisteredAt:    time.Now().Format(time.RFC3339),
+			OriginalDueDate: l.NextDue,
+			User:            user,
+			Reason:          w.Reason,
+		})
+		refreshLoanState(&l, ref)
+
+		err = saveLoanVersioned(ctx, &l)
+		if err == errLoanConflict {
+			continue
+		}
+		if err != nil {
+			return l, err
+		}
+		logUserAction("BAIXA COMO PERDA", user, fmt.Sprintf("Contrato %s (%s): capital R$ %s, juros R$ %s, encargos R$ %s. Motivo: %s",
+			l.ID, l.Client, w.Capital, w.Interest, w.Charges, w.Reason))
+		return l, nil
+	}
+	return Loan{}, errLoanConflict
+}
+
+// blacklistWrittenOffClient inclui o CPF do cliente do contrato na lista negra,
+// com o motivo preenchido pela baixa. Não duplica um CPF já listado, então pode
+// ser repetida quando a gravação do contrato é refeita.
+func blacklistWrittenOffClient(ctx context.Context, l Loan, risk, user string) bool {
+	var c Client
+	if err := clientCollection.FindOne(ctx, bson.M{"name": l.Client}).Decode(&c); err != nil || c.CPF == "" {
+		logUserAction("LISTA NEGRA", user, fmt.Sprintf("Contrato %s: cliente %s sem CPF cadastrado, inclusão ignorada", l.ID, l.Client))
+		return false
+	}
+	if hit, _ := findBlacklisted(ctx, c.CPF); hit != nil {
+		return true
+	}
+	if risk == "" {
+		risk = "Alto"
+	}
+	entry := BlacklistEntry{
+		ID:        primitive.NewObjectID().Hex(),
+		Name:      c.Name,
+		CPF:       c.CPF,
+		CPFDigits: document.Digits(c.CPF),
+		Reason:    "Inadimplência - Baixa como Perda",
+		Date:      today().Format("02/01/2006"),
+		Risk:      risk,
+		Notes:     fmt.Sprintf("Contrato %s baixado como perda: %s", l.ID, l.WriteOff.Reason),
+	}
+	if _, err := blacklistCollection.InsertOne(ctx, entry); err != nil {
+		return false
+	}
+	logUserAction("LISTA NEGRA", user, fmt.Sprintf("CPF %s (%s) incluído pela baixa do contrato %s", c.CPF, c.Name, l.ID))
+	return true
+}
+
+type recoveryRequest struct {
+	Date      string      `json:"date"`
+	Amount    money.Cents `json:"amount"`
+	Note      string      `json:"note"`
+	RequestID string      `json:"requestId"`
+}
+
+// registerRecovery lança um valor recuperado em contrato baixado como perda.
+// Um requestId repetido devolve o contrato sem lançar de novo.
+func registerRecovery(ctx context.Context, id string, req recoveryRequest, user string) (Loan, bool, error) {
+	if req.Amount <= 0 {
+		return Loan{}, false, errInvalidAmount
+	}
+	date := today()
+	if req.Date != "" {
+		d, err := parseLoanDate(req.Date)
+		if err != nil {
+			return Loan{}, false, errInvalidPaymentDate
+		}
+		date = d
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		l, err := findLoan(ctx, id)
+		if err != nil {
+			return l, false, err
+		}
+		if l.WriteOff == nil {
+			return l, false, errNotWrittenOff
+		}
+		if req.RequestID != "" {
+			for _, rec := range l.Recoveries {
+				if rec.RequestID == req.RequestID {
+					return l, false, nil
+				}
+			}
+		}
+		l.Recoveries = append(l.Recoveries, Recovery{
+			Date:         date.Format(finance.DateLayout),
+			Amount:       req.Amount,
+			Note:         req.Note,
+			User:         user,
+			RegisteredAt: time.Now().Format(time.RFC3339),
+			RequestID:    req.RequestID,
+		})
+
+		err = saveLoanVersioned(ctx, &l)
+		if err == errLoanConflict {
+			continue
+		}
+		if err != nil {
+			return l, false, err
+		}
+		logUserAction("RECUPERAÇÃO DE CRÉDITO", user, fmt.Sprintf("Contrato %s (%s): R$ %s recuperados em %s",
+			l.ID, l.Client, req.Amount, date.Format("02/01/2006")))
+		return l, true, nil
+	}
+	return Loan{}, false, errLoanConflict
+}
+
+// recovered soma as recuperações do contrato.
+func (l Loan) recovered() money.Cents {
+	var total money.Cents
+	for _, r := range l.Recoveries {
+		total += r.Amount
+	}
+	return total
+}
+
+func loanWriteOffHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	user := currentUser(r)
+	if !isAdminUser(ctx, user) {
+		logAction("ACESSO NEGADO ADMIN", user)
+		http.Error(w, "Acesso restrito a Administradores.", http.StatusForbidden)
+		return
+	}
+	var req writeOffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	l, err := writeOffLoan(ctx, id, req, user)
+	switch err {
+	case nil:
+	case errLoanNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errInvalidWriteOff:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errAlreadyWrittenOff, errLoanConflict:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errNothingToWriteOff:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(l)
+}
+
+func loanRecoveriesHandler(w http.ResponseWriter, r *http.Request, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		l, err := findLoan(ctx, id)
+		if err == errLoanNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Erro ao buscar contrato", http.StatusInternalServerError)
+			return
+		}
+		if l.Recoveries == nil {
+			l.Recoveries = []Recovery{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(l.Recoveries)
+	case http.MethodPost:
+		var req recoveryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		l, created, err := registerRecovery(ctx, id, req, currentUser(r))
+		switch err {
+		case nil:
+		case errLoanNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errInvalidAmount, errInvalidPaymentDate:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errNotWrittenOff:
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case errLoanConflict:
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(l)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// --- Relatório de Perdas ---
+
+type lossReportRow struct {
+	LoanID    string      `json:"loanId"`
+	Client    string      `json:"client"`
+	Date      string      `json:"date"`
+	Reason    string      `json:"reason"`
+	Capital   money.Cents `json:"capital"`
+	Interest  money.Cents `json:"interest"`
+	Charges   money.Cents `json:"charges"`
+	Recovered money.Cents `json:"recovered"`
+	NetLoss   money.Cents `json:"netLoss"` // capital baixado menos o recuperado
+}
+
+type lossReport struct {
+	Count     int             `json:"count"`
+	Capital   money.Cents     `json:"capital"`
+	Interest  money.Cents     `json:"interest"`
+	Charges   money.Cents     `json:"charges"`
+	Recovered money.Cents     `json:"recovered"`
+	NetLoss   money.Cents     `json:"netLoss"`
+	Loans     []lossReportRow `json:"loans"`
+}
+
+// lossesReportHandler consolida os contratos baixados como perda no período
+// (?from=&to=, datas AAAA-MM-DD, ambos opcionais) e o quanto já foi recuperado.
+// Juros e encargos baixados não eram receita realizada, então a perda líquida é
+// medida sobre o capital.
+func lossesReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := loanCollection.Find(ctx, bson.M{"writeOff": bson.M{"$exists": true}})
+	if err != nil {
+		http.Error(w, "Erro ao buscar contratos", http.StatusInternalServerError)
+		return
+	}
+	var loans []Loan
+	cursor.All(ctx, &loans)
+
+	rep := lossReport{Loans: []lossReportRow{}}
+	for _, l := range loans {
+		wo := l.WriteOff
+		if wo == nil || (from != "" && wo.Date < from) || (to != "" && wo.Date > to) {
+			continue
+		}
+		row := lossReportRow{
+			LoanID:    l.ID,
+			Client:    l.Client,
+			Date:      wo.Date,
+			Reason:    wo.Reason,
+			Capital:   wo.Capital,
+			Interest:  wo.Interest,
+			Charges:   wo.Charges,
+			Recovered: l.recovered(),
+		}
+		row.NetLoss = row.Capital - row.Recovered
+
+		rep.Count++
+		rep.Capital += row.Capital
+		rep.Interest += row.Interest
+		rep.Charges += row.Charges
+		rep.Recovered += row.Recovered
+		rep.NetLoss += row.NetLoss
+		rep.Loans = append(rep.Loans, row)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rep)
+}
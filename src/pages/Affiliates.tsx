@@ -59,8 +59,8 @@ const Affiliates = () => {
       try {
         await affiliateService.delete(id);
         setAffiliates(affiliates.filter(a => a.id !== id));
-      } catch (err) {
-        alert('Falha ao remover parceiro.');
+      } catch (err: any) {
+        alert('Falha ao remover parceiro: ' + (err.response?.data || err.message));
       }
     }
   };
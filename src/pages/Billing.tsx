@@ -14,7 +14,7 @@ import { generateContractPDF, generatePromissoryPDF } from '../utils/generatePDF
 import Layout from '../components/Layout';
 import Modal from '../components/Modal';
 import { calculateOverdueValue, formatMoney, calculateRealBalance, calculateInstallmentBreakdown, calculateCapitalBalance } from '../utils/finance';
-import { loanService, clientService, affiliateService, Loan, Client, PaymentRecord, Affiliate } from '../services/api';
+import { loanService, clientService, affiliateService, authHeaders, Loan, Client, Affiliate } from '../services/api';
 
 interface LoanExtended extends Loan {
   diffDays: number;
@@ -79,7 +79,7 @@ const getInstanceToken = async (
 const sendWhatsappApi = async (
   name: string,
   phone: string,
-  contract: string,
+  loanId: string,
   lateDays: number,
   updatedAmount: number,
   dateVencimento: string,
@@ -90,9 +90,10 @@ const sendWhatsappApi = async (
   // Enviar via API
   const response = await fetch(getApiUrl+"/api/message", {
     method: "POST",
-    headers: { "Content-Type": "application/json" },
+    headers: { "Content-Type": "application/json", ...authHeaders() },
     body: JSON.stringify({
       userConectado: companyName,
+      loanId: loanId, // valor, atraso e vencimento são calculados no servidor
       phone: phone,
       delay: 2,
       name: name,
@@ -133,6 +134,7 @@ const Billing = () => {
   const [isLoadingList, setIsLoadingList] = useState(false);
 
   const [payDate, setPayDate] = useState(''); 
+  const [payRequestId, setPayRequestId] = useState('');
   const [payCapital, setPayCapital] = useState(''); 
   const [payInterest, setPayInterest] = useState(''); 
   const [payTotal, setPayTotal] = useState(0); 
@@ -192,7 +194,7 @@ const handleWhatsApp = async (loan: LoanExtended, snowball: any) => {
     await sendWhatsappApi(
       client.name,
       cleanPhone,
-      contractCode,
+      loan.id,
       diffDays,
       loan.installmentValue,
       formattedDate,
@@ -371,7 +373,8 @@ const handleWhatsApp = async (loan: LoanExtended, snowball: any) => {
   const renderSchedule = (loan: Loan) => {
       const historyPayments = (loan.history || []).filter(h => h.type === 'Parcela' || h.type === 'Amortização' || h.type === 'Juros');
       const isSimple = loan.interestType === 'SIMPLE';
-      const totalOriginal = isSimple ? '∞' : loan.installments + historyPayments.length;
+      const remaining = loan.remainingInstallments ?? loan.installments;
+      const totalOriginal = isSimple ? '∞' : (loan.remainingInstallments != null ? loan.installments : loan.installments + historyPayments.length);
 
       const schedule = [];
 
@@ -390,7 +393,7 @@ const handleWhatsApp = async (loan: LoanExtended, snowball: any) => {
           const today = new Date();
           today.setHours(0,0,0,0);
           
-          for (let i = 0; i < loan.installments; i++) {
+          for (let i = 0; i < remaining; i++) {
               const stepDate = new Date(y, m - 1, d);
               if (loan.frequency === 'SEMANAL') stepDate.setDate(stepDate.getDate() + (7 * i));
               else if (loan.frequency === 'DIARIO') stepDate.setDate(stepDate.getDate() + (1 * i));
@@ -594,6 +597,7 @@ const handleWhatsApp = async (loan: LoanExtended, snowball: any) => {
     const offsetMs = now.getTimezoneOffset() * 60 * 1000;
     const localISOTime = (new Date(now.getTime() - offsetMs)).toISOString().slice(0, 16);
     setPayDate(localISOTime);
+    setPayRequestId(`${loan.id}-${now.getTime()}`);
 
     const breakdown = calculateInstallmentBreakdown(loan);
     let initialInterest = breakdown.interest;
@@ -680,11 +684,6 @@ const handleWhatsApp = async (loan: LoanExtended, snowball: any) => {
         if (!userConfirmed) return;
     }
 
-    let updatedLoan = { ...selectedLoan };
-    updatedLoan.totalPaidCapital = (updatedLoan.totalPaidCapital || 0) + valCapital;
-    updatedLoan.totalPaidInterest = (updatedLoan.totalPaidInterest || 0) + valInterest;
-
-    const balance = updatedLoan.amount - updatedLoan.totalPaidCapital;
     let noteText = `Baixa Manual. Ref: ${new Date(payDate).toLocaleString('pt-BR')}`;
     const cycleCompletedNow = isPayingFullInstallment || (totalInterestInCycle >= (totalTargetInterest - 0.10));
 
@@ -692,57 +691,13 @@ const handleWhatsApp = async (loan: LoanExtended, snowball: any) => {
     else if (cycleCompletedNow) noteText += ` [QUITAÇÃO MENSAL]`;
     else noteText += ` [PARCIAL]`;
 
-    const originalDueStr = selectedLoan.nextDue; 
-
-    if (balance <= 0.10) {
-        updatedLoan.status = 'Quitado';
-        updatedLoan.installments = 0;
-    } else {
-        updatedLoan.status = 'Em Dia'; 
-        
-        if (valCapital > 0 || settleInterest || cycleCompletedNow) {
-             if (selectedLoan.status === 'Acordo') {
-                 const startDate = new Date(selectedLoan.startDate);
-                 startDate.setMinutes(startDate.getMinutes() + startDate.getTimezoneOffset());
-                 const originalDay = startDate.getDate();
-                 const payDateObj = new Date(payDate);
-                 let nextMonth = payDateObj.getMonth() + 1;
-                 let nextYear = payDateObj.getFullYear();
-                 if (nextMonth > 11) { nextMonth = 0; nextYear++; }
-                 const nextDueStr = `${nextYear}-${String(nextMonth + 1).padStart(2, '0')}-${String(originalDay).padStart(2, '0')}`;
-                 updatedLoan.nextDue = nextDueStr;
-                 noteText += " (Retorno ao ciclo original)";
-                 updatedLoan.agreementValue = 0;
-             } else {
-                 const currentDue = new Date(updatedLoan.nextDue);
-                 if (updatedLoan.frequency === 'SEMANAL') currentDue.setDate(currentDue.getDate() + 7);
-                 else if (updatedLoan.frequency === 'DIARIO') currentDue.setDate(currentDue.getDate() + 1);
-                 else currentDue.setMonth(currentDue.getMonth() + 1);
-                 updatedLoan.nextDue = currentDue.toISOString().split('T')[0];
-             }
-             
-             const isSimple = updatedLoan.interestType === 'SIMPLE';
-             if (!isSimple || valCapital > 0) updatedLoan.installments = Math.max(0, updatedLoan.installments - 1);
-        }
-    }
-
-    const newRecord: PaymentRecord = {
-        date: new Date(payDate).toISOString(),
-        amount: valTotal, capitalPaid: valCapital, interestPaid: valInterest,
-        type: (valCapital > 0 && valInterest > 0) ? 'Parcela' : (valCapital > 0 ? 'Amortização' : 'Juros'),
-        note: noteText, registeredAt: new Date().toISOString(),
-        originalDueDate: originalDueStr 
-    };
-
-    updatedLoan.history = [...(selectedLoan.history || []), newRecord];
-
     try {
-        await loanService.update(
-            selectedLoan.id, 
-            updatedLoan, 
-            'BAIXA DE PAGAMENTO', 
-            `Recebeu R$ ${valTotal.toFixed(2)} (Capital: R$ ${valCapital.toFixed(2)}, Juros: R$ ${valInterest.toFixed(2)}) do cliente ${selectedLoan.client}`
-        );
+        // Alocação nas parcelas, totais, vencimento e situação são calculados no servidor.
+        const updatedLoan = await loanService.registerPayment(selectedLoan.id, {
+            amount: valTotal, date: payDate, note: noteText,
+            order: (valInterest === 0 && valCapital > 0) ? ['capital'] : undefined,
+            requestId: payRequestId
+        });
         setLoans(prev => prev.map(l => l.id === updatedLoan.id ? updatedLoan : l));
         setIsPaymentModalOpen(false);
         setSelectedLoan(updatedLoan);
@@ -766,34 +721,23 @@ const handleWhatsApp = async (loan: LoanExtended, snowball: any) => {
 
     if (!confirmUndo) return;
 
-    let updatedLoan = { ...selectedLoan };
-
-    updatedLoan.agreementValue = 0;
-    updatedLoan.status = 'Em Dia'; 
-
-    if (lastEntry.type !== 'Acordo') {
-        updatedLoan.totalPaidCapital = Math.max(0, (updatedLoan.totalPaidCapital || 0) - (lastEntry.capitalPaid || 0));
-        updatedLoan.totalPaidInterest = Math.max(0, (updatedLoan.totalPaidInterest || 0) - (lastEntry.interestPaid || 0));
-        
-        const isSimple = updatedLoan.interestType === 'SIMPLE';
-        if (!isSimple || (lastEntry.capitalPaid && lastEntry.capitalPaid > 0)) {
-            updatedLoan.installments += 1;
-        }
-    }
-
-    if (lastEntry.originalDueDate) {
-        updatedLoan.nextDue = lastEntry.originalDueDate;
-    }
-
-    updatedLoan.history = history.slice(0, -1);
-
     try {
-        await loanService.update(
-            selectedLoan.id, 
-            updatedLoan, 
-            'ESTORNO DE REGISTRO', 
-            `Desfez o último registro de ${lastEntry.type} do cliente ${selectedLoan.client}.`
-        );
+        let updatedLoan: Loan;
+        if (lastEntry.type === 'Acordo' && (selectedLoan.agreementValue || 0) > 0) {
+            // Acordo simples: zerar o valor extra desfaz no servidor e volta o vencimento.
+            updatedLoan = await loanService.update(
+                selectedLoan.id,
+                { ...selectedLoan, agreementValue: 0 },
+                'ESTORNO DE REGISTRO',
+                `Desfez o acordo do cliente ${selectedLoan.client}.`
+            );
+        } else {
+            // Pagamentos não são apagados: o servidor lança um estorno compensatório.
+            if (lastEntry.reversed || lastEntry.reversalOf != null || lastEntry.amount <= 0 || lastEntry.type === 'Abertura') { alert("Este registro não pode ser estornado."); return; }
+            const justification = window.prompt("Justificativa do estorno:");
+            if (!justification || !justification.trim()) { alert("Justificativa obrigatória."); return; }
+            updatedLoan = await loanService.reversePayment(selectedLoan.id, history.length - 1, justification.trim());
+        }
         setLoans(prev => prev.map(l => l.id === updatedLoan.id ? updatedLoan : l));
         setSelectedLoan(updatedLoan);
         alert("✅ Reversão concluída no servidor!");
@@ -812,25 +756,11 @@ const handleWhatsApp = async (loan: LoanExtended, snowball: any) => {
 
   const confirmAgreement = async () => {
       if (!selectedLoan || !agreementDate || !agreementValue) return;
-      let updatedLoan = { ...selectedLoan };
-      
-      const originalDateAntesDoAcordo = updatedLoan.nextDue;
-      
-      updatedLoan.status = 'Acordo';
-      updatedLoan.nextDue = agreementDate;
-      updatedLoan.agreementValue = parseFloat(agreementValue);
-      
-      const note = `ACORDO: Vencimento alterado de ${formatDisplayDate(originalDateAntesDoAcordo)} para ${formatDisplayDate(agreementDate)} com valor EXTRA de R$ ${formatMoney(parseFloat(agreementValue))}.`;
-      
-      updatedLoan.history = [...(selectedLoan.history || []), { 
-          date: new Date().toISOString(), amount: 0, type: 'Acordo', note, capitalPaid: 0, interestPaid: 0,
-          originalDueDate: originalDateAntesDoAcordo 
-      }];
-      
       try {
-          await loanService.update(
+          // Novo vencimento, situação e registro no histórico ficam a cargo do servidor.
+          const updatedLoan = await loanService.update(
               selectedLoan.id, 
-              updatedLoan, 
+              { ...selectedLoan, agreementDate, agreementValue: parseFloat(agreementValue) }, 
               'NOVO ACORDO', 
               `Negociou com ${selectedLoan.client}. Vencimento alterado para ${formatDisplayDate(agreementDate)} com valor extra de R$ ${formatMoney(parseFloat(agreementValue))}.`
           );
@@ -1140,7 +1070,7 @@ const handleWhatsApp = async (loan: LoanExtended, snowball: any) => {
                         </td>
                         <td className="p-4 text-center">
                           <span className="bg-slate-100 text-slate-600 px-2 py-1 rounded text-xs font-bold border border-slate-200">
-                            {loan.installments}x
+                            {loan.remainingInstallments ?? loan.installments}x
                           </span>
                         </td>
                         <td className="p-4 text-center">
@@ -86,7 +86,7 @@ const Dashboard = () => {
       let count = 0;
       
       const baseAmount = loan.status === 'Acordo' ? loan.installmentValue + (loan.agreementValue || 0) : loan.installmentValue;
-      const remainingInstallments = loan.interestType === 'SIMPLE' ? 999 : (loan.installments || 1);
+      const remainingInstallments = loan.interestType === 'SIMPLE' ? 999 : ((loan.remainingInstallments ?? loan.installments) || 1);
       
       while (tempDue < today) {
           const dateStr = tempDue.toISOString().split('T')[0];
@@ -208,7 +208,7 @@ const Dashboard = () => {
             profToAdd = futureProfitTotal;  // Somente lucro futuro
             overToAdd = totalOverdue;       // Bola de neve das atrasadas
         } else {
-            const limit = loan.interestType === 'SIMPLE' ? 60 : (loan.installments || 1);
+            const limit = loan.interestType === 'SIMPLE' ? 60 : ((loan.remainingInstallments ?? loan.installments) || 1);
             for (let i = 0; i < limit; i++) {
                 if (currentDue >= startFilter && currentDue <= endFilter) {
                     hasMatch = true;
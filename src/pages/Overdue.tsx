@@ -15,7 +15,7 @@ import {
 } from "lucide-react";
 import Layout from "../components/Layout";
 import Modal from "../components/Modal";
-import { loanService, clientService, Loan, Client } from "../services/api";
+import { loanService, clientService, authHeaders, Loan, Client } from "../services/api";
 import { calculateOverdueValue, formatMoney } from "../utils/finance";
 
 interface LoanExtended extends Loan {
@@ -32,7 +32,7 @@ const getApiUrl = localStorage.getItem("getApiUrl") || "";
 const sendWhatsappApi = async (
   name: string,
   phone: string,
-  contract: string,
+  loanId: string,
   lateDays: number,
   updatedAmount: number,
   companyName: string,
@@ -40,9 +40,10 @@ const sendWhatsappApi = async (
 ) => {
   const response = await fetch(getApiUrl+`/api/message`, {
     method: "POST",
-    headers: { "Content-Type": "application/json" },
+    headers: { "Content-Type": "application/json", ...authHeaders() },
     body: JSON.stringify({
       userConectado: companyName,
+      loanId: loanId, // valor, atraso e vencimento são calculados no servidor
       phone: phone,
       delay: 1,
       name: name,
@@ -95,7 +96,7 @@ const Overdue = () => {
         : loan.installmentValue;
 
     const remainingInstallments =
-      loan.interestType === "SIMPLE" ? 999 : loan.installments || 1;
+      loan.interestType === "SIMPLE" ? 999 : (loan.remainingInstallments ?? loan.installments) || 1;
 
     while (tempDue < today) {
       const dateStr = tempDue.toISOString().split("T")[0];
@@ -239,7 +240,7 @@ const Overdue = () => {
       await sendWhatsappApi(
         loan.client,
         client.phone,
-        contractCode,
+        loan.id,
         diffDays,
         snowball.totalUpdated,
         instance.instanceName,
@@ -1,14 +1,71 @@
 import axios from 'axios';
 
+export interface PaymentAllocation {
+  agreement?: string;
+  installment: number;
+  fine?: number;
+  mora?: number;
+  correction?: number;
+  interest?: number;
+  capital?: number;
+  discount?: number;
+}
+
 export interface PaymentRecord {
   date: string;
   amount: number;
-  type: 'Entrada' | 'Renovação' | 'Amortização' | 'Quitação' | 'Abertura' | 'Parcela' | 'Juros' | 'Acordo' | string;
+  type: 'Entrada' | 'Renovação' | 'Amortização' | 'Quitação' | 'Abertura' | 'Parcela' | 'Juros' | 'Acordo' | 'Pausa' | 'Perda' | string;
   note?: string;
   capitalPaid?: number;
   interestPaid?: number;
   registeredAt?: string;
   originalDueDate?: string; 
+  finePaid?: number;
+  moraPaid?: number;
+  correctionPaid?: number;
+  allocations?: PaymentAllocation[];
+  requestId?: string;
+  user?: string;
+  reversed?: boolean;
+  reversalOf?: number;
+  reason?: string;
+}
+
+export interface LoanInstallment {
+  number: number;
+  dueDate: string;
+  expectedCapital: number;
+  expectedInterest: number;
+  paidCapital: number;
+  paidInterest: number;
+  paidFine?: number;
+  paidMora?: number;
+  paidCorrection?: number;
+  interestDiscount?: number;
+  paidAt?: string;
+  status: 'Pendente' | 'Parcial' | 'Pago' | 'Atrasado' | 'Renegociada';
+  agreementId?: string;
+}
+
+export interface Agreement {
+  id: string;
+  createdAt: string;
+  createdBy: string;
+  status: 'Ativo' | 'Cumprido';
+  frozenCapital: number;
+  frozenInterest: number;
+  frozenFine: number;
+  frozenMora: number;
+  frozenCorrection?: number;
+  correctionIndex?: CorrectionIndex;
+  chargesDiscountRate: number;
+  discount: number;
+  total: number;
+  installmentValue: number;
+  frequency: string;
+  coveredInstallments: number[];
+  schedule: LoanInstallment[];
+  note?: string;
 }
 
 export interface Loan {
@@ -16,10 +73,11 @@ export interface Loan {
   client: string;
   amount: number;
   installments: number;
+  remainingInstallments?: number;
   interestRate: number;
   startDate: string;
   nextDue: string;
-  status: 'Em Dia' | 'Atrasado' | 'Pago' | 'Pendente' | 'Acordo' | 'Quitado';
+  status: 'Em Dia' | 'Atrasado' | 'Pago' | 'Pendente' | 'Acordo' | 'Quitado' | 'Perda';
   installmentValue: number;
   fineRate?: number;
   moraInterestRate?: number;
@@ -30,19 +88,190 @@ export interface Loan {
   totalPaidInterest?: number;
   totalPaidCapital?: number;
   history?: PaymentRecord[];
+  schedule?: LoanInstallment[];
+  agreements?: Agreement[];
+  totalPaidCharges?: number;
+  version?: number;
+  refinancedFrom?: string;
+  refinancedBy?: string;
+  applicationId?: string;
+  creditLimitExceeded?: boolean;
+  blacklistHits?: BlacklistHit[]; // alertas (risco Médio/Baixo) na criação
+  creditLimitOverride?: CreditLimitOverride;
+  product?: string;
+  checklist?: ChecklistSnapshot;
+  writeOff?: WriteOff;
+  recoveries?: Recovery[];
   frequency?: 'DIARIO' | 'SEMANAL' | 'MENSAL';
+  dueDateRule?: DueDateRule;
+  graceDays?: number;
+  graceRule?: GraceRule;
+  graceInterest?: number;
+  correctionIndex?: CorrectionIndex;
   projectedProfit?: number;
+  cet?: number;
+  iofDaily?: number;
+  iofAdditional?: number;
+  iof?: number;
   agreementDate?: string; 
   agreementValue?: number;
+  affiliateId?: string; // vínculo pelo ID ou pelo código do afiliado
+  affiliateCode?: string;
   affiliateName?: string;
-  affiliateFee?: number; 
+  affiliateCommission?: CommissionTerms; // condições congeladas na indicação
+  affiliateFee?: number; // comissão devida; calculada pelo servidor quando vinculado
   affiliateNotes?: string; 
-  interestType?: 'PRICE' | 'SIMPLE'; 
+  interestType?: 'PRICE' | 'SAC' | 'LINEAR' | 'SIMPLE'; 
   guarantorName?: string;
   guarantorCPF?: string;
+  guarantorCPFDigits?: string;
   guarantorAddress?: string;
 }
 
+export interface ScheduleInstallment {
+  number: number;
+  dueDate: string;
+  payment: number;
+  interest: number;
+  capital: number;
+  balance: number;
+}
+
+export interface LoanSchedule {
+  interestType: string;
+  frequency: string;
+  periodicRate: number;
+  installmentValue: number;
+  totalInterest: number;
+  totalPayable: number;
+  installments: ScheduleInstallment[];
+}
+
+export interface PayoffInstallment {
+  agreement?: string;
+  number: number;
+  dueDate: string;
+  daysLate: number;
+  capital: number;
+  interest: number;
+  fine: number;
+  mora: number;
+  correction?: number;
+  total: number;
+}
+
+export type CorrectionIndex = 'IPCA' | 'IGPM' | 'CDI';
+
+export interface IndexCorrection {
+  index: CorrectionIndex;
+  base: number;
+  correction: number;
+  corrected: number;
+  missingMonths?: string[];
+}
+
+export interface IndexRate {
+  index: CorrectionIndex;
+  month: string; // AAAA-MM
+  rate: number;  // variação no mês, em %
+}
+
+export interface LoanPayoff {
+  loanId: string;
+  date: string;
+  maxDaysLate: number;
+  overdueCapital: number;
+  overdueInterest: number;
+  fine: number;
+  mora: number;
+  correction: number;
+  overdueTotal: number;
+  outstandingTotal: number;
+  nextInstallment?: PayoffInstallment;
+  installments: PayoffInstallment[];
+  corrections: IndexCorrection[];
+}
+
+export interface SettlementLine {
+  agreement?: string;
+  number: number;
+  dueDate: string;
+  capital: number;
+  interest: number;
+  interestDiscount: number;
+  fine: number;
+  mora: number;
+  correction?: number;
+  total: number;
+}
+
+export interface SettlementQuote {
+  loanId: string;
+  date: string;
+  expiresAt: string;
+  remainingCapital: number;
+  interest: number;
+  interestDiscount: number;
+  fine: number;
+  mora: number;
+  correction: number;
+  total: number;
+  lines: SettlementLine[];
+}
+
+export interface WriteOff {
+  date: string;
+  user: string;
+  reason: string;
+  capital: number;
+  interest: number;
+  charges: number;
+  blacklisted?: boolean;
+}
+
+export interface Recovery {
+  date: string;
+  amount: number;
+  note?: string;
+  user?: string;
+  registeredAt: string;
+  requestId?: string;
+}
+
+export type DueDateRule = 'MANTER' | 'PROXIMO_UTIL';
+
+// Juros da carência: CAPITALIZAR dilui nas parcelas, DIFERIR cobra na última
+export type GraceRule = 'CAPITALIZAR' | 'DIFERIR';
+
+export interface Holiday {
+  date: string; // AAAA-MM-DD (só no ano) ou MM-DD (todo ano)
+  name: string;
+}
+
+export interface HolidayCalendar {
+  year: number;
+  holidays: Holiday[];
+  local: Holiday[];
+}
+
+export interface LoanSimulation {
+  startDate: string;
+  firstDueDate: string;
+  dueDateRule: DueDateRule;
+  graceDays?: number;
+  graceRule?: GraceRule;
+  graceInterest?: number;
+  installmentValue: number;
+  projectedProfit: number;
+  totalPayable: number;
+  iofDaily: number;
+  iofAdditional: number;
+  iof: number;
+  cet: number;
+  cetMonthly: number;
+  schedule: LoanSchedule;
+}
+
 export interface ClientDoc {
   name: string;
   data: string; 
@@ -53,6 +282,7 @@ export interface Client {
   id: number | string;
   name: string;
   cpf: string;
+  cpfDigits?: string;
   rg?: string;
   email: string;
   phone: string;
@@ -65,6 +295,68 @@ export interface Client {
   cep?: string;
   observations?: string;
   documents?: ClientDoc[];
+  creditLimit?: number; // 0 = sem limite
+  blacklistHits?: BlacklistHit[];
+  score?: ClientScore;
+}
+
+export interface ScoreFactor {
+  name: string;
+  points: number;
+  max: number;
+  detail: string;
+}
+
+export interface ClientScore {
+  score: number; // 0 a 1000
+  grade: 'A' | 'B' | 'C' | 'D' | 'E';
+  factors: ScoreFactor[];
+  calculatedAt: string;
+}
+
+export interface ExposureLoan {
+  loanId: string;
+  client: string;
+  status: string;
+  openCapital: number;
+}
+
+export interface ClientRef {
+  id: number;
+  name: string;
+  cpf?: string;
+  phone?: string;
+}
+
+export interface DuplicateCandidate {
+  clients: [ClientRef, ClientRef];
+  reasons: ('CPF' | 'Telefone' | 'Nome')[];
+  nameSimilarity: number;
+}
+
+export interface MergeResult {
+  client: Client;
+  loans: number;
+  applications: number;
+  documents: number;
+}
+
+export interface ClientExposure {
+  clientId: number;
+  name: string;
+  cpf: string;
+  creditLimit: number;
+  exposure: number;
+  available?: number;
+  loans: ExposureLoan[];
+}
+
+export interface CreditLimitOverride {
+  justification: string;
+  user?: string;
+  date?: string;
+  limit?: number;
+  exposure?: number;
 }
 
 export interface Affiliate {
@@ -74,9 +366,39 @@ export interface Affiliate {
   phone?: string;
   code?: string;
   pixKey?: string;
+  commissionBase?: CommissionBase;
+  commissionRate?: number; // % sobre a base
   fixedCommission?: number;
-  earned?: number;
-  status?: string;
+  referrals?: number; // mantido pelo servidor
+  earned?: number; // mantido pelo servidor
+  status?: 'Ativo' | 'Inativo' | string;
+}
+
+// ORIGINACAO: % do valor do contrato na contratação; JUROS: % dos juros recebidos.
+export type CommissionBase = 'ORIGINACAO' | 'JUROS';
+
+export interface CommissionTerms {
+  base: CommissionBase;
+  rate: number;
+  fixed: number;
+  rollover?: number; // saldo rolado no refinanciamento, fora da base de originação
+}
+
+export interface AffiliateCommissionLine {
+  loanId: string;
+  client: string;
+  startDate: string;
+  status: string;
+  refinancedFrom?: string;
+  amount: number;
+  interestReceived: number;
+  terms: CommissionTerms;
+  commission: number;
+}
+
+export interface AffiliateStatement {
+  affiliate: Affiliate;
+  loans: AffiliateCommissionLine[];
 }
 
 export interface LogEntry {
@@ -91,9 +413,31 @@ export interface BlacklistEntry {
   id: string;
   name: string;
   cpf: string;
+  cpfDigits?: string;
   reason: string;
   date: string;
-  riskLevel?: string;
+  riskLevel?: 'Alto' | 'Médio' | 'Baixo' | string;
+  notes?: string;
+}
+
+// Ocorrência da triagem na lista negra. Risco Alto bloqueia a criação (422 com
+// erro no campo); Médio e Baixo só ficam registrados no cadastro.
+export interface BlacklistHit {
+  entryId: string;
+  role: 'Cliente' | 'Avalista';
+  field: string;
+  name: string;
+  cpf: string;
+  reason: string;
+  riskLevel: 'Alto' | 'Médio' | 'Baixo' | string;
+  blocked: boolean;
+}
+
+// Resposta de erro por campo: 400 (inválido), 409 (CPF já cadastrado) ou 422
+// (lista negra). Ex.: { cpf: 'CPF inválido: ...' }.
+export interface FieldErrorResponse {
+  error: string;
+  fields: Record<string, string>;
 }
 
 export interface SystemUser {
@@ -114,9 +458,7 @@ const api = axios.create({
   }
 });
 
-// Interceptor de Autenticação
-api.interceptors.request.use(
-  (config) => {
+const getAuthToken = (): string | null => {
     let token = localStorage.getItem('token');
     if (!token) {
         const session = localStorage.getItem('lms_active_session');
@@ -124,6 +466,19 @@ api.interceptors.request.use(
             try { token = JSON.parse(session).token; } catch (e) {}
         }
     }
+    return token;
+};
+
+// Cabeçalho de autenticação para chamadas feitas com fetch fora do axios
+export const authHeaders = (): Record<string, string> => {
+    const token = getAuthToken();
+    return token ? { Authorization: `Bearer ${token}` } : {};
+};
+
+// Interceptor de Autenticação
+api.interceptors.request.use(
+  (config) => {
+    const token = getAuthToken();
     if (token) config.headers.Authorization = `Bearer ${token}`;
     return config;
   },
@@ -229,6 +584,10 @@ export const loanService = {
     const response = await api.get('/loans');
     return response.data || [];
   },
+  simulate: async (params: { amount: number; interestRate: number; installments: number; frequency?: string; interestType?: string; startDate?: string; firstDueDate?: string; dueDateRule?: DueDateRule; graceDays?: number; graceRule?: GraceRule }): Promise<LoanSimulation> => {
+    const response = await api.post('/loans/simulate', params);
+    return response.data;
+  },
   create: async (loan: Loan): Promise<Loan> => {
     const response = await api.post('/loans', loan);
     await registerSystemLog('CONTRATO CRIADO', `Valor de R$ ${loan.amount} para o cliente ${loan.client}`);
@@ -242,6 +601,64 @@ export const loanService = {
     await registerSystemLog(action, details);
     return response.data;
   },
+  getSchedule: async (id: string): Promise<LoanSchedule> => {
+    const response = await api.get(`/loans/${id}/schedule`);
+    return response.data;
+  },
+  // Baixa calculada no servidor; requestId evita baixa dupla em clique repetido.
+  registerPayment: async (id: string, payment: { amount: number; date?: string; note?: string; order?: string[]; requestId?: string }): Promise<Loan> => {
+    const response = await api.post(`/loans/${id}/payments`, payment);
+    return response.data;
+  },
+  // index é a posição do lançamento em loan.history
+  reversePayment: async (id: string, index: number, justification: string): Promise<Loan> => {
+    const response = await api.post(`/loans/${id}/payments/${index}/reverse`, { justification });
+    return response.data;
+  },
+  getPayoff: async (id: string, date?: string): Promise<LoanPayoff> => {
+    const response = await api.get(`/loans/${id}/payoff`, { params: date ? { date } : {} });
+    return response.data;
+  },
+  getAgreements: async (id: string): Promise<Agreement[]> => {
+    const response = await api.get(`/loans/${id}/agreements`);
+    return response.data || [];
+  },
+  createAgreement: async (id: string, agreement: { installments: number; installmentValue?: number; firstDueDate: string; frequency?: string; chargesDiscountRate?: number; correctionIndex?: CorrectionIndex; note?: string }): Promise<Loan> => {
+    const response = await api.post(`/loans/${id}/agreements`, agreement);
+    return response.data;
+  },
+  getSettlementQuote: async (id: string, date?: string): Promise<SettlementQuote> => {
+    const response = await api.get(`/loans/${id}/settlement-quote`, { params: date ? { date } : {} });
+    return response.data;
+  },
+  settle: async (id: string, settlement: { date?: string; amount?: number; note?: string; requestId?: string }): Promise<Loan> => {
+    const response = await api.post(`/loans/${id}/settlement-quote`, settlement);
+    return response.data;
+  },
+  refinance: async (id: string, params: { installments: number; newMoney?: number; interestRate?: number; frequency?: string; interestType?: string; firstDueDate?: string; dueDateRule?: DueDateRule; graceDays?: number; graceRule?: GraceRule; affiliateFee?: number; note?: string; requestId?: string; creditLimitOverride?: CreditLimitOverride }): Promise<{ closed: Loan; loan: Loan; quote: SettlementQuote }> => {
+    const response = await api.post(`/loans/${id}/refinance`, params);
+    return response.data;
+  },
+  writeOff: async (id: string, params: { reason: string; blacklist?: boolean; riskLevel?: string }): Promise<Loan> => {
+    const response = await api.post(`/loans/${id}/write-off`, params);
+    return response.data;
+  },
+  getRecoveries: async (id: string): Promise<Recovery[]> => {
+    const response = await api.get(`/loans/${id}/recoveries`);
+    return response.data || [];
+  },
+  addRecovery: async (id: string, recovery: { amount: number; date?: string; note?: string; requestId?: string }): Promise<Loan> => {
+    const response = await api.post(`/loans/${id}/recoveries`, recovery);
+    return response.data;
+  },
+  paymentHoliday: async (id: string, params: { periods?: number; rule?: GraceRule; reason?: string; requestId?: string }): Promise<Loan> => {
+    const response = await api.post(`/loans/${id}/payment-holiday`, params);
+    return response.data;
+  },
+  getInstallments: async (id: string): Promise<LoanInstallment[]> => {
+    const response = await api.get(`/loans/${id}/installments`);
+    return response.data || [];
+  },
   delete: async (id: string): Promise<void> => {
     await api.delete(`/loans/${id}`);
     await registerSystemLog('EXCLUSÃO CRÍTICA', `Contrato ID ${id} foi completamente deletado do sistema.`);
@@ -253,6 +670,23 @@ export const clientService = {
     const response = await api.get('/clients');
     return response.data || [];
   },
+  getScore: async (id: number | string): Promise<ClientScore> => {
+    const response = await api.get(`/clients/${id}/score`);
+    return response.data;
+  },
+  getExposure: async (id: number | string): Promise<ClientExposure> => {
+    const response = await api.get(`/clients/${id}/exposure`);
+    return response.data;
+  },
+  findDuplicates: async (clientId?: number | string): Promise<DuplicateCandidate[]> => {
+    const response = await api.get('/clients/duplicates', { params: clientId ? { clientId } : {} });
+    return response.data || [];
+  },
+  // Mantém o cliente `id` e incorpora nele o cadastro `duplicateId` (só administradores).
+  merge: async (id: number | string, duplicateId: number | string): Promise<MergeResult> => {
+    const response = await api.post(`/clients/${id}/merge`, { duplicateId: Number(duplicateId) });
+    return response.data;
+  },
   create: async (client: Client): Promise<Client> => {
     const response = await api.post('/clients', client);
     await registerSystemLog('CLIENTE CRIADO', `Cadastrou o cliente: ${client.name}`);
@@ -274,6 +708,14 @@ export const affiliateService = {
     const response = await api.get('/affiliates');
     return response.data || [];
   },
+  getById: async (id: string): Promise<Affiliate> => {
+    const response = await api.get(`/affiliates/${id}`);
+    return response.data;
+  },
+  getCommissions: async (id: string): Promise<AffiliateStatement> => {
+    const response = await api.get(`/affiliates/${id}/commissions`);
+    return response.data;
+  },
   create: async (affiliate: Affiliate): Promise<Affiliate> => {
     const response = await api.post('/affiliates', affiliate);
     return response.data;
@@ -287,14 +729,101 @@ export const affiliateService = {
   }
 };
 
+export interface ChecklistItem {
+  id: string;
+  label: string;
+  mandatory: boolean;
+}
+
+export interface ChecklistTemplate {
+  id?: string;
+  name: string;
+  version?: number;
+  product?: string;
+  minAmount?: number;
+  maxAmount?: number; // 0 = sem teto
+  items: ChecklistItem[];
+  updatedAt?: string;
+  updatedBy?: string;
+}
+
+export interface ChecklistSnapshot {
+  templateId: string;
+  name: string;
+  version: number;
+  items: ChecklistItem[];
+  checked: string[];
+  approvedBy: string;
+  approvedAt: string;
+}
+
+export type LoanApplicationStatus = 'Em Análise' | 'Aprovada' | 'Rejeitada';
+
+export interface ApplicationCheck {
+  name: string;
+  passed: boolean;
+  detail?: string;
+}
+
+export interface ApplicationEvent {
+  date: string;
+  user: string;
+  status: LoanApplicationStatus;
+  note?: string;
+}
+
+export interface LoanApplication {
+  id: string;
+  status: LoanApplicationStatus;
+  loan: Loan;
+  simulation: LoanSimulation;
+  checks: ApplicationCheck[];
+  checklist?: ChecklistTemplate;
+  score?: ClientScore;
+  createdAt: string;
+  createdBy: string;
+  decidedAt?: string;
+  decidedBy?: string;
+  decisionReason?: string;
+  loanId?: string;
+  events: ApplicationEvent[];
+}
+
+export const applicationService = {
+  getAll: async (status?: LoanApplicationStatus): Promise<LoanApplication[]> => {
+    const response = await api.get('/loan-applications', { params: status ? { status } : {} });
+    return response.data || [];
+  },
+  get: async (id: string): Promise<LoanApplication> => {
+    const response = await api.get(`/loan-applications/${id}`);
+    return response.data;
+  },
+  create: async (loan: Partial<Loan>): Promise<LoanApplication> => {
+    const response = await api.post('/loan-applications', loan);
+    return response.data;
+  },
+  approve: async (id: string, reason: string, checklist: string[] = []): Promise<LoanApplication> => {
+    const response = await api.post(`/loan-applications/${id}/approve`, { reason, checklist });
+    return response.data;
+  },
+  reject: async (id: string, reason: string): Promise<LoanApplication> => {
+    const response = await api.post(`/loan-applications/${id}/reject`, { reason });
+    return response.data;
+  }
+};
+
 export const blacklistService = {
   getAll: async (): Promise<BlacklistEntry[]> => {
     const response = await api.get('/blacklist');
     return response.data || [];
   },
+  getById: async (id: string): Promise<BlacklistEntry> => {
+    const response = await api.get(`/blacklist/${id}`);
+    return response.data;
+  },
+  // Inclusão, alteração e remoção são registradas no log pelo servidor.
   create: async (entry: BlacklistEntry): Promise<BlacklistEntry> => {
     const response = await api.post('/blacklist', entry);
-    await registerSystemLog('LISTA NEGRA', `Bloqueou o CPF ${entry.cpf} - Motivo: ${entry.reason}`);
     return response.data;
   },
   update: async (id: string, entry: BlacklistEntry): Promise<BlacklistEntry> => {
@@ -303,7 +832,6 @@ export const blacklistService = {
   },
   delete: async (id: string): Promise<void> => {
     await api.delete(`/blacklist/${id}`);
-    await registerSystemLog('LISTA NEGRA', `Removeu o bloqueio do ID ${id}`);
   }
 };
 
@@ -338,12 +866,108 @@ export const settingsService = {
     await registerSystemLog('CONFIGURAÇÕES', `Alterou os parâmetros vitais do sistema`);
     return response.data;
   },
+  getHolidays: async (year?: number): Promise<HolidayCalendar> => {
+    const response = await api.get('/settings/holidays', { params: year ? { year } : {} });
+    return response.data;
+  },
+  saveLocalHolidays: async (holidays: Holiday[]): Promise<Holiday[]> => {
+    const response = await api.put('/settings/holidays', holidays);
+    return response.data;
+  },
+  getChecklists: async (): Promise<ChecklistTemplate[]> => {
+    const response = await api.get('/settings/checklists');
+    return response.data || [];
+  },
+  resolveChecklist: async (amount: number, product?: string): Promise<ChecklistTemplate | null> => {
+    const response = await api.get('/settings/checklists', { params: { amount, product: product || '' } });
+    return response.data;
+  },
+  saveChecklists: async (templates: ChecklistTemplate[]): Promise<ChecklistTemplate[]> => {
+    const response = await api.put('/settings/checklists', templates);
+    return response.data;
+  },
   restoreBackup: async (backupData: any) => {
     const response = await api.post('/admin/restore', backupData, { timeout: 60000 });
     return response.data;
   }
 };
 
+export interface IOFReportRow {
+  loanId: string;
+  client: string;
+  startDate: string;
+  amount: number;
+  iofDaily: number;
+  iofAdditional: number;
+  iof: number;
+  estimated?: boolean;
+}
+
+export interface IOFReport {
+  month: string;
+  count: number;
+  amount: number;
+  iofDaily: number;
+  iofAdditional: number;
+  iof: number;
+  loans: IOFReportRow[];
+}
+
+export interface LossReportRow {
+  loanId: string;
+  client: string;
+  date: string;
+  reason: string;
+  capital: number;
+  interest: number;
+  charges: number;
+  recovered: number;
+  netLoss: number;
+}
+
+export interface LossReport {
+  count: number;
+  capital: number;
+  interest: number;
+  charges: number;
+  recovered: number;
+  netLoss: number;
+  loans: LossReportRow[];
+}
+
+export const reportService = {
+  getAgreements: async (from?: string, to?: string) => {
+    const response = await api.get('/reports/agreements', { params: { from, to } });
+    return response.data;
+  },
+  getIOF: async (month?: string): Promise<IOFReport> => {
+    const response = await api.get('/reports/iof', { params: { month } });
+    return response.data;
+  },
+  getLosses: async (from?: string, to?: string): Promise<LossReport> => {
+    const response = await api.get('/reports/losses', { params: { from, to } });
+    return response.data;
+  }
+};
+
+export const indexService = {
+  list: async (index?: CorrectionIndex): Promise<IndexRate[]> => {
+    const response = await api.get('/indexes', { params: { index } });
+    return response.data;
+  },
+  replace: async (index: CorrectionIndex, rows: { month: string; rate: number }[]): Promise<IndexRate[]> => {
+    const response = await api.put(`/indexes/${index}`, rows);
+    return response.data;
+  },
+  importCSV: async (index: CorrectionIndex, csv: string): Promise<{ index: string; imported: number }> => {
+    const response = await api.post(`/indexes/${index}/import`, csv, { headers: { 'Content-Type': 'text/csv' } });
+    return response.data;
+  },
+  removeMonth: async (index: CorrectionIndex, month: string) => {
+    await api.delete(`/indexes/${index}/${month}`);
+  }
+};
+
 export const dashboardService = {
   getSummary: async () => {
     const response = await api.get('/dashboard/summary');
@@ -222,6 +222,9 @@ export const generateContractPDF = async (loan: Loan, clientData?: Client) => {
   addText(`1.2 Fica acordado entre as Partes que o Valor do Mútuo será acrescido de uma taxa de remuneração de ${loan.interestRate}% a.m.`);
   addText(`1.3 O Valor do Mútuo deverá ser restituído em sua integralidade pelo MUTUÁRIO ao MUTUANTE, respeitando-se os juros e correção pactuados na cláusula 1.2 acima.`);
   addText(`1.4 Caso o MUTUÁRIO deixe de pagar integralmente no prazo estipulado, o saldo devedor ficará sujeito a juros moratórios à taxa de ${loan.moraInterestRate ?? 1}% ao mês, multa de mora na ordem de ${loan.fineRate ?? 2}% sobre o valor atualizado do débito e correção monetária.`);
+  if (loan.iof) {
+    addText(`1.5 Sobre a operação incide IOF de ${formatMoney(loan.iof)} (parcela diária ${formatMoney(loan.iofDaily || 0)} e adicional ${formatMoney(loan.iofAdditional || 0)}), sendo o Custo Efetivo Total (CET) de ${(loan.cet ?? 0).toFixed(2)}% a.a.`);
+  }
 
   addSpace(5);
   addText("Cláusula Segunda – DO PRAZO DE VIGÊNCIA E PAGAMENTO", true);